@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	table "xdcc-cli/table"
+	xdcc "xdcc-cli/xdcc"
+)
+
+func printBotsUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: bots [--network name]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "--"
+	}
+	return d.Round(time.Second).String()
+}
+
+// execBots prints the transfer history of the bots, from the most to the least reputable.
+func execBots(args []string) {
+	botsCmd := flag.NewFlagSet("bots", flag.ExitOnError)
+	network := botsCmd.String("network", "", "only show the bots of the given network")
+
+	args = parseFlags(botsCmd, args)
+	if len(args) != 0 {
+		printBotsUsageAndExit(botsCmd)
+	}
+
+	stats, err := botStats.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	reputations := xdcc.Reputations(stats)
+	keys := make([]string, 0, len(stats))
+	for key, s := range stats {
+		if *network == "" || s.Network == *network {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return reputations[keys[i]] > reputations[keys[j]]
+	})
+
+	printer := table.NewTablePrinter([]string{"Bot", "Succeeded", "Failed", "Reliability", "Avg Speed", "Avg Queue", "Last Used"})
+	for _, key := range keys {
+		s := stats[key]
+		bot := s.Bot()
+
+		speed := "--"
+		if s.SpeedSamples > 0 {
+			speed = formatSize(int64(s.AverageSpeed)) + "/s"
+		}
+
+		printer.AddRow(table.Row{
+			bot.String(),
+			strconv.Itoa(s.Successes),
+			strconv.Itoa(s.Failures),
+			FloatToString(100*s.Reliability()) + "%",
+			speed,
+			formatDuration(s.AverageQueueWait),
+			s.LastUsed.Format("2006-01-02 15:04"),
+		})
+	}
+	printer.Print()
+}
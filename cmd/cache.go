@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"xdcc-cli/search"
+)
+
+func printCacheUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: cache stats\n" +
+		"       cache prune\n" +
+		"       cache clear\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func execCache(args []string) {
+	cacheCmd := flag.NewFlagSet("cache", flag.ExitOnError)
+
+	args = parseFlags(cacheCmd, args)
+	if len(args) != 1 {
+		printCacheUsageAndExit(cacheCmd)
+	}
+
+	cache := search.NewResultCache(search.DefaultCacheDir(), userConfig.Cache.TTL)
+
+	switch args[0] {
+	case "stats":
+		stats, err := cache.Stats()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		hitRate := 0.0
+		if total := stats.Hits + stats.Misses; total > 0 {
+			hitRate = 100 * float64(stats.Hits) / float64(total)
+		}
+
+		fmt.Printf("path:     %s\n", cache.Dir)
+		fmt.Printf("ttl:      %s\n", cache.TTL)
+		fmt.Printf("entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("size:     %s\n", formatSize(stats.Size))
+		fmt.Printf("hits:     %d\n", stats.Hits)
+		fmt.Printf("misses:   %d\n", stats.Misses)
+		fmt.Printf("hit rate: %s%%\n", FloatToString(hitRate))
+	case "prune", "clear":
+		removed, err := cache.Prune(args[0] == "clear")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("removed %d entries.\n", removed)
+	default:
+		printCacheUsageAndExit(cacheCmd)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"xdcc-cli/search"
+)
+
+// resultColumn is an optional column of the search results table.
+type resultColumn struct {
+	header string
+	value  func(info *search.XdccFileInfo) string
+}
+
+var resultColumns = map[string]resultColumn{
+	"provider": {"Provider", func(info *search.XdccFileInfo) string {
+		return info.Provider
+	}},
+	"gets": {"Gets", func(info *search.XdccFileInfo) string {
+		if info.Gets == 0 {
+			return "--"
+		}
+		return strconv.Itoa(info.Gets)
+	}},
+	"speed": {"Bot Speed", func(info *search.XdccFileInfo) string {
+		if info.BotSpeed == 0 {
+			return "--"
+		}
+		return formatSize(info.BotSpeed) + "/s"
+	}},
+	"seen": {"Last Seen", func(info *search.XdccFileInfo) string {
+		if info.LastSeen == nil {
+			return "--"
+		}
+		return info.LastSeen.Format(time.RFC3339)
+	}},
+}
+
+// parseResultColumns parses a comma separated list of optional column names.
+func parseResultColumns(s string) ([]resultColumn, error) {
+	columns := make([]resultColumn, 0)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		column, ok := resultColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("no such column: %s", name)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func columnHeaders(headers []string, columns []resultColumn) []string {
+	for _, c := range columns {
+		headers = append(headers, c.header)
+	}
+	return headers
+}
+
+func columnWidths(widths []int, columns []resultColumn) []int {
+	for range columns {
+		widths = append(widths, -1)
+	}
+	return widths
+}
+
+func columnValues(row []string, columns []resultColumn, info *search.XdccFileInfo) []string {
+	for _, c := range columns {
+		row = append(row, c.value(info))
+	}
+	return row
+}
+
+// printJSON prints v as indented JSON, for consumption by other programs.
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"xdcc-cli/config"
+)
+
+func printConfigUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: config [path]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+// timeouts of the transfers, when not configured
+const (
+	defaultRequestTimeout = 2 * time.Minute
+	defaultQueueTimeout   = 10 * time.Minute
+)
+
+// durationOr returns d, or def if d is not set.
+func durationOr(d time.Duration, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// execConfig prints the effective configuration, merging the configuration file with the defaults,
+// or the location of the configuration file.
+func execConfig(args []string) {
+	configCmd := flag.NewFlagSet("config", flag.ExitOnError)
+
+	args = parseFlags(configCmd, args)
+	if len(args) > 1 || (len(args) == 1 && args[0] != "path") {
+		printConfigUsageAndExit(configCmd)
+	}
+
+	path := config.DefaultPath()
+	if len(args) == 1 {
+		fmt.Println(path)
+		return
+	}
+
+	if fileExists(path) {
+		fmt.Printf("# %s\n", path)
+	} else {
+		fmt.Printf("# %s (not found, showing defaults)\n", path)
+	}
+
+	if err := userConfig.Encode(os.Stdout); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"xdcc-cli/config"
+	"xdcc-cli/search"
+	xdcc "xdcc-cli/xdcc"
+)
+
+// botStats records the outcome of every transfer, which is then used to prefer reliable bots.
+var botStats = xdcc.NewBotStatsStore(xdcc.DefaultBotStatsPath())
+
+// loadBotReputations scores the bots by their transfer history. An unreadable history is ignored.
+func loadBotReputations() search.BotReputations {
+	stats, err := botStats.Load()
+	if err != nil {
+		return nil
+	}
+	return search.BotReputations(xdcc.Reputations(stats))
+}
+
+// searchFlags are the switches shared by the subcommands searching files through the providers.
+type searchFlags struct {
+	providers stringListFlag
+	limit     *int
+	noCache   *bool
+	refresh   *bool
+}
+
+func addSearchFlags(flagSet *flag.FlagSet) *searchFlags {
+	f := &searchFlags{}
+	flagSet.Var(&f.providers, "provider", "only search through the named provider of the configuration file, optionally overriding its options as in name:base_url=URL,timeout=10s,weight=2,max_pages=3 (can be repeated)")
+	f.limit = flagSet.Int("limit", 0, "maximum number of results retrieved from each provider, following result pages (0 retrieves the default pages of each provider)")
+	f.noCache = flagSet.Bool("no-cache", false, "neither read nor store cached search results")
+	f.refresh = flagSet.Bool("refresh", false, "ignore cached search results, replacing them with fresh ones")
+	return f
+}
+
+func addReleaseFilterFlags(flagSet *flag.FlagSet) *search.ReleaseFilter {
+	f := &search.ReleaseFilter{}
+	flagSet.StringVar(&f.Resolution, "resolution", "", "only keep files of the given resolution (e.g. 1080p)")
+	flagSet.IntVar(&f.Season, "season", 0, "only keep episodes of the given season")
+	flagSet.IntVar(&f.Episode, "episode", 0, "only keep the given episode")
+	return f
+}
+
+// userConfig is the configuration file, loaded on startup.
+var userConfig = config.Default()
+
+func loadConfigOrExit() *config.Config {
+	path := config.DefaultPath()
+	conf, err := config.Load(path)
+	if err != nil {
+		fmt.Printf("config: %s: %s\n", path, err)
+		os.Exit(1)
+	}
+	return conf
+}
+
+// newResultCache returns the cache configured in conf, or nil if it is disabled.
+func newResultCache(conf *config.Config) *search.ResultCache {
+	if conf.Cache.Disabled {
+		return nil
+	}
+	return search.NewResultCache(search.DefaultCacheDir(), conf.Cache.TTL)
+}
+
+// newSearchEngine creates an aggregator of the providers enabled in the configuration file,
+// or of the ones selected through --provider only, if any, together with the cache of their results.
+func (f *searchFlags) newSearchEngine() (*search.ProviderAggregator, *search.ResultCache) {
+	conf := userConfig
+
+	var cache *search.ResultCache
+	if !*f.noCache {
+		cache = newResultCache(conf)
+	}
+
+	if cache != nil {
+		cache.Refresh = *f.refresh
+	}
+
+	providers, names, err := search.ParseProviderSpecs(conf.Providers, f.providers)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	searchEngine, err := search.NewProviderAggregatorFromConfig(providers, names, cache)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	searchEngine.SetLimit(*f.limit)
+	searchEngine.SetBotReputations(loadBotReputations())
+	return searchEngine, cache
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"xdcc-cli/search"
+	table "xdcc-cli/table"
+	xdcc "xdcc-cli/xdcc"
+)
+
+const defaultFetchCandidates = 3
+
+func printCandidates(candidates []search.Candidate) {
+	printer := table.NewTablePrinter([]string{"Score", "File Name", "Size", "URL"})
+	printer.SetMaxWidths(append([]int{-1}, defaultColWidths...))
+
+	for _, c := range candidates {
+		printer.AddRow(table.Row{strconv.FormatFloat(c.Score, 'f', 2, 64), c.Name, formatSize(c.Size), c.URL.String()})
+	}
+	printer.Print()
+}
+
+func printFetchUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: fetch keyword1 keyword2 ... [-o path] [-n candidates] [-l] [--ssl-only]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func execFetch(args []string) {
+	fetchCmd := flag.NewFlagSet("fetch", flag.ExitOnError)
+	path := fetchCmd.String("o", userConfig.OutPath, "output folder of dowloaded file")
+	maxCandidates := fetchCmd.Int("n", defaultFetchCandidates, "maximum number of candidates to try")
+	listOnly := fetchCmd.Bool("l", false, "only list the best candidates, without downloading")
+	timeout := fetchCmd.Duration("timeout", durationOr(userConfig.RequestTimeout, defaultRequestTimeout), "time to wait for a bot to start sending before trying the next candidate")
+	queueTimeout := fetchCmd.Duration("queue-timeout", durationOr(userConfig.QueueTimeout, defaultQueueTimeout), "time to wait in the queue of a bot before switching to another source")
+	sslOnly := fetchCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection")
+
+	searchOpts := addSearchFlags(fetchCmd)
+	releaseFilter := addReleaseFilterFlags(fetchCmd)
+
+	args = parseFlags(fetchCmd, args)
+	if len(args) < 1 {
+		printFetchUsageAndExit(fetchCmd)
+	}
+
+	searchEngine, cache := searchOpts.newSearchEngine()
+	res, _ := searchEngine.Search(args)
+	if cache != nil {
+		cache.SaveStats()
+	}
+
+	candidates := search.RankCandidates(search.FilterResults(res, *releaseFilter))
+	if len(candidates) == 0 {
+		fmt.Println("fetch: no file found.")
+		os.Exit(1)
+	}
+
+	if *maxCandidates > 0 && len(candidates) > *maxCandidates {
+		candidates = candidates[:*maxCandidates]
+	}
+
+	if *listOnly {
+		printCandidates(candidates)
+		return
+	}
+
+	tried := make(map[xdcc.IRCFile]bool)
+	for _, c := range candidates {
+		if tried[c.URL] {
+			continue
+		}
+
+		mirrors := search.FindMirrors(res, c.XdccFileInfo)
+		tried[c.URL] = true
+		for _, mirror := range mirrors {
+			tried[mirror] = true
+		}
+
+		transfer := xdcc.NewTransfer(xdcc.Config{
+			File:           c.URL,
+			Mirrors:        mirrors,
+			OutPath:        *path,
+			SSLOnly:        *sslOnly,
+			RequestTimeout: *timeout,
+			QueueTimeout:   *queueTimeout,
+			Stats:          botStats,
+		})
+
+		err := doTransfer(transfer)
+		if err == nil {
+			return
+		}
+		fmt.Printf("%s: %s\n", c.URL.String(), err)
+	}
+
+	fmt.Println("fetch: no candidate could be downloaded.")
+	os.Exit(1)
+}
@@ -0,0 +1,15 @@
+package main
+
+import "strings"
+
+// stringListFlag is a flag which can be repeated, collecting all of its values.
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
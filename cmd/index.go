@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"xdcc-cli/search"
+	xdcc "xdcc-cli/xdcc"
+)
+
+func printIndexUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: index add irc://network/channel/bot [packlist-url]\n" +
+		"       index remove irc://network/channel/bot\n" +
+		"       index update [--ssl-only]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func loadIndexOrExit(path string) *search.Index {
+	idx, err := search.LoadIndex(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return idx
+}
+
+func saveIndexOrExit(idx *search.Index, path string) {
+	if err := idx.Save(path); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func parseBotURLOrExit(urlStr string) xdcc.IRCBot {
+	bot, err := xdcc.ParseBotURL(urlStr)
+	if err != nil {
+		fmt.Printf("no valid bot url: %s\n", urlStr)
+		os.Exit(1)
+	}
+	return *bot
+}
+
+func execIndex(args []string) {
+	indexCmd := flag.NewFlagSet("index", flag.ExitOnError)
+	sslOnly := indexCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection")
+
+	args = parseFlags(indexCmd, args)
+	if len(args) < 1 {
+		printIndexUsageAndExit(indexCmd)
+	}
+
+	path := search.DefaultIndexPath()
+	idx := loadIndexOrExit(path)
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || len(args) > 3 {
+			printIndexUsageAndExit(indexCmd)
+		}
+
+		src := search.IndexSource{Bot: parseBotURLOrExit(args[1])}
+		if len(args) == 3 {
+			src.PacklistURL = args[2]
+		}
+		idx.AddSource(src)
+	case "remove":
+		if len(args) != 2 {
+			printIndexUsageAndExit(indexCmd)
+		}
+		idx.RemoveSource(parseBotURLOrExit(args[1]))
+	case "update":
+		errs := idx.Update(*sslOnly)
+		for bot, err := range errs {
+			fmt.Printf("%s: %s\n", bot.String(), err)
+		}
+		fmt.Printf("%d files indexed from %d bots\n", len(idx.Files), len(idx.Sources)-len(errs))
+	default:
+		printIndexUsageAndExit(indexCmd)
+	}
+
+	saveIndexOrExit(idx, path)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	xdcc "xdcc-cli/xdcc"
+)
+
+func printInfoUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: info url [--ssl-only]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func execInfo(args []string) {
+	infoCmd := flag.NewFlagSet("info", flag.ExitOnError)
+	sslOnly := infoCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection")
+
+	args = parseFlags(infoCmd, args)
+	if len(args) != 1 {
+		printInfoUsageAndExit(infoCmd)
+	}
+
+	url, err := xdcc.ParseURL(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	info, err := xdcc.QueryInfo(*url, *sslOnly)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, key := range info.Keys {
+		fmt.Printf("%-16s %s\n", key+":", info.Fields[key])
+	}
+}
+
+// preflightCheck asks the bot offering file for the details of the pack, returning an error
+// if the pack is not available and filling conf with the reported checksums.
+func preflightCheck(file xdcc.IRCFile, conf *xdcc.Config) error {
+	info, err := xdcc.QueryInfo(file, conf.SSLOnly)
+	if err != nil {
+		return err
+	}
+
+	conf.CRC32 = info.CRC32
+	conf.MD5 = info.MD5
+	return nil
+}
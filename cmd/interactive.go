@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"xdcc-cli/search"
+	table "xdcc-cli/table"
+	"xdcc-cli/util"
+)
+
+var errInvalidSelection = errors.New("invalid selection")
+
+const selectionPrompt = "select files (e.g. 1 3-5), /pattern to filter, empty to reset filter, q to quit: "
+
+func printNumberedResults(results []search.XdccFileInfo) {
+	printer := table.NewTablePrinter([]string{"#", "File Name", "Size", "URL"})
+	printer.SetMaxWidths(append([]int{-1}, defaultColWidths...))
+
+	for i, fileInfo := range results {
+		printer.AddRow(table.Row{strconv.Itoa(i + 1), fileInfo.Name, formatSize(fileInfo.Size), fileInfo.URL.String()})
+	}
+	printer.Print()
+}
+
+// parseSelection parses a list of indices and ranges (e.g. "1,3-5 7") into zero based indices.
+func parseSelection(s string, n int) ([]int, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	indices := make([]int, 0)
+	for _, field := range fields {
+		lo, hi := field, field
+		if idx := strings.Index(field, "-"); idx > 0 {
+			lo, hi = field[:idx], field[idx+1:]
+		}
+
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidSelection, field)
+		}
+
+		end, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidSelection, field)
+		}
+
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("%w: %s", errInvalidSelection, field)
+		}
+
+		for i := start; i <= end; i++ {
+			indices = append(indices, i-1)
+		}
+	}
+	return indices, nil
+}
+
+func filterResults(results []search.XdccFileInfo, pattern string) []search.XdccFileInfo {
+	filtered := make([]search.XdccFileInfo, 0)
+	for _, res := range results {
+		if util.FuzzyMatch(pattern, res.Name) {
+			filtered = append(filtered, res)
+		}
+	}
+	return filtered
+}
+
+// selectFiles lets the user pick a subset of the search results from the standard input.
+func selectFiles(results []search.XdccFileInfo) []search.XdccFileInfo {
+	shown := results
+	printNumberedResults(shown)
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print(selectionPrompt)
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "q":
+			return nil
+		case line == "":
+			shown = results
+			printNumberedResults(shown)
+		case strings.HasPrefix(line, "/"):
+			shown = filterResults(results, strings.TrimPrefix(line, "/"))
+			printNumberedResults(shown)
+		default:
+			indices, err := parseSelection(line, len(shown))
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			selected := make([]search.XdccFileInfo, 0, len(indices))
+			for _, idx := range indices {
+				selected = append(selected, shown[idx])
+			}
+			return selected
+		}
+	}
+}
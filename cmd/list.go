@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"xdcc-cli/search"
+	xdcc "xdcc-cli/xdcc"
+)
+
+func printListUsageAndExit(flagSet *flag.FlagSet) {
+	fmt.Printf("usage: list irc://network/channel/bot [--sort keys] [--ssl-only]\n\nFlag set:\n")
+	flagSet.PrintDefaults()
+	os.Exit(0)
+}
+
+func execList(args []string) {
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	sortKeys := listCmd.String("sort", "slot", "comma separated list of sort keys (name, size, slot), each optionally followed by :asc or :desc")
+	sslOnly := listCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection")
+
+	args = parseFlags(listCmd, args)
+	if len(args) != 1 {
+		printListUsageAndExit(listCmd)
+	}
+
+	bot, err := xdcc.ParseBotURL(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	keys, err := search.ParseSortKeys(*sortKeys)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	lines, err := xdcc.ListPacks(*bot, *sslOnly)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	res := search.ParsePacklist(*bot, lines)
+	search.SortResults(res, keys)
+	printResults(res, nil)
+}
@@ -16,16 +16,13 @@ import (
 	xdcc "xdcc-cli/xdcc"
 )
 
-var searchEngine *search.ProviderAggregator
+var defaultColWidths []int = []int{100, 10, -1}
 
-func init() {
-	searchEngine = search.NewProviderAggregator(
-		&search.XdccEuProvider{},
-		&search.SunXdccProvider{},
-	)
-}
+// streamColWidths are used when results are printed as they arrive, since the widths
+// of the columns cannot be adjusted to their content.
+var streamColWidths []int = []int{60, 10, 70}
 
-var defaultColWidths []int = []int{100, 10, -1}
+const streamExtraColWidth = 27
 
 func FloatToString(value float64) string {
 	if value-float64(int64(value)) > 0 {
@@ -49,40 +46,224 @@ func formatSize(size int64) string {
 	return FloatToString(float64(size)) + "B"
 }
 
+const defaultSortKeys = "relevance"
+
+const (
+	groupByFile    = "file"
+	groupByEpisode = "episode"
+)
+
 func execSearch(args []string) {
 	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
 	sortByFilename := searchCmd.Bool("s", false, "sort results by filename")
+	sortKeys := searchCmd.String("sort", defaultSortKeys, "comma separated list of sort keys (relevance, name, size, network, bot, slot, provider, gets), each optionally followed by :asc or :desc")
+	interactive := searchCmd.Bool("I", false, "interactively select the files to download among the results")
+	path := searchCmd.String("o", userConfig.OutPath, "output folder of dowloaded files (interactive mode only)")
+	sslOnly := searchCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection (interactive mode and bot searches only)")
+
+	var bots stringListFlag
+	searchCmd.Var(&bots, "bot", "also search the packs of the bot at the given irc://network/channel/bot url through XDCC SEARCH (can be repeated)")
+
+	var triggerChannels stringListFlag
+	searchCmd.Var(&triggerChannels, "trigger", "also search the bots of the channel at the given irc://network/channel url through a search trigger (can be repeated)")
+	trigger := searchCmd.String("trigger-cmd", search.DefaultSearchTrigger, "search trigger issued in the channels passed to --trigger")
+
+	searchOpts := addSearchFlags(searchCmd)
+	stream := searchCmd.Bool("stream", false, "print results as soon as they are found, without sorting or grouping them")
+	noGroup := searchCmd.Bool("no-group", false, "print a row for each source, rather than grouping the sources of the same file")
+	groupBy := searchCmd.String("group-by", groupByFile, "group results by file or by episode, listing the files of the same episode together")
+	releaseFilter := addReleaseFilterFlags(searchCmd)
+	columnList := searchCmd.String("columns", "", "comma separated list of extra columns (provider, gets, speed, seen)")
+	jsonOutput := searchCmd.Bool("json", false, "print results as JSON")
 
 	args = parseFlags(searchCmd, args)
 
-	printer := table.NewTablePrinter([]string{"File Name", "Size", "URL"})
-	printer.SetMaxWidths(defaultColWidths)
-
 	if len(args) < 1 {
 		fmt.Println("search: no keyword provided.")
 		os.Exit(1)
 	}
 
+	if *sortByFilename {
+		*sortKeys = "name"
+	}
+
+	keys, err := search.ParseSortKeys(*sortKeys)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	columns, err := parseResultColumns(*columnList)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	searchEngine, cache := searchOpts.newSearchEngine()
+	if cache != nil {
+		defer cache.SaveStats()
+	}
+
+	if len(bots) > 0 {
+		searchEngine.AddProvider(newBotSearchProvider(bots, *sslOnly))
+	}
+
+	if len(triggerChannels) > 0 {
+		searchEngine.AddProvider(newTriggerSearchProvider(triggerChannels, *trigger, *sslOnly))
+	}
+
+	if *groupBy != groupByFile && *groupBy != groupByEpisode {
+		fmt.Printf("search: no such grouping: %s\n", *groupBy)
+		os.Exit(1)
+	}
+
+	if *stream && !*interactive && !*jsonOutput {
+		printResultStream(searchEngine.SearchStream(args), releaseFilter, columns)
+		return
+	}
+
 	res, _ := searchEngine.Search(args)
-	for _, fileInfo := range res {
-		printer.AddRow(table.Row{fileInfo.Name, formatSize(fileInfo.Size), fileInfo.URL.String()})
+	res = search.FilterResults(res, *releaseFilter)
+	search.SortResults(res, keys)
+
+	if *interactive {
+		choices := res
+		if !*noGroup {
+			choices = make([]search.XdccFileInfo, 0)
+			for _, g := range search.GroupResults(res) {
+				choices = append(choices, g.XdccFileInfo)
+			}
+		}
+
+		files := make([][]xdcc.IRCFile, 0)
+		for _, fileInfo := range selectFiles(choices) {
+			files = append(files, append([]xdcc.IRCFile{fileInfo.URL}, search.FindMirrors(res, fileInfo)...))
+		}
+		downloadFiles(files, xdcc.Config{OutPath: *path, SSLOnly: *sslOnly, Stats: botStats}, false)
+		return
 	}
 
-	sortColumn := 2
-	if *sortByFilename {
-		sortColumn = 0
+	switch {
+	case *jsonOutput && *noGroup:
+		printJSON(res)
+	case *jsonOutput && *groupBy == groupByEpisode:
+		printJSON(search.GroupResultsByEpisode(res))
+	case *jsonOutput:
+		printJSON(search.GroupResults(res))
+	case *noGroup:
+		printResults(res, columns)
+	case *groupBy == groupByEpisode:
+		printEpisodes(search.GroupResultsByEpisode(res), columns)
+	default:
+		printGroupedResults(search.GroupResults(res), columns)
+	}
+}
+
+func newBotSearchProvider(urls []string, sslOnly bool) *search.BotSearchProvider {
+	provider := &search.BotSearchProvider{SSLOnly: sslOnly}
+	for _, urlStr := range urls {
+		bot, err := xdcc.ParseBotURL(urlStr)
+		if err != nil {
+			fmt.Printf("no valid bot url: %s\n", urlStr)
+			os.Exit(1)
+		}
+		provider.Bots = append(provider.Bots, *bot)
+	}
+	return provider
+}
+
+func newTriggerSearchProvider(urls []string, trigger string, sslOnly bool) *search.TriggerSearchProvider {
+	provider := &search.TriggerSearchProvider{SSLOnly: sslOnly}
+	for _, urlStr := range urls {
+		network, channel, err := xdcc.ParseChannelURL(urlStr)
+		if err != nil {
+			fmt.Printf("no valid channel url: %s\n", urlStr)
+			os.Exit(1)
+		}
+
+		provider.Channels = append(provider.Channels, search.TriggerChannel{
+			Network: network,
+			Channel: channel,
+			Trigger: trigger,
+		})
 	}
-	printer.SortByColumn(sortColumn)
+	return provider
+}
 
+func printResults(results []search.XdccFileInfo, columns []resultColumn) {
+	printer := table.NewTablePrinter(columnHeaders([]string{"File Name", "Size", "URL"}, columns))
+	printer.SetMaxWidths(columnWidths(defaultColWidths, columns))
+
+	for _, fileInfo := range results {
+		printer.AddRow(columnValues(table.Row{fileInfo.Name, formatSize(fileInfo.Size), fileInfo.URL.String()}, columns, &fileInfo))
+	}
 	printer.Print()
 }
 
-func transferLoop(transfer xdcc.Transfer) {
+// printGroupedResults prints a row for each file, listing the urls of all its sources,
+// separated by commas, as accepted by the get subcommand.
+func printGroupedResults(groups []search.ResultGroup, columns []resultColumn) {
+	printer := table.NewTablePrinter(columnHeaders([]string{"File Name", "Size", "Sources", "URL"}, columns))
+	printer.SetMaxWidths(columnWidths([]int{defaultColWidths[0], defaultColWidths[1], -1, defaultColWidths[2]}, columns))
+
+	for _, g := range groups {
+		merged := g.Merged()
+		printer.AddRow(columnValues(table.Row{g.Name, formatSize(g.Size), strconv.Itoa(len(g.Sources)), groupURLs(g)}, columns, &merged))
+	}
+	printer.Print()
+}
+
+func groupURLs(g search.ResultGroup) string {
+	urls := make([]string, 0, len(g.Sources))
+	for _, url := range g.URLs() {
+		urls = append(urls, url.String())
+	}
+	return strings.Join(urls, ",")
+}
+
+// printEpisodes prints the files of each episode next to each other, grouping the sources of each file.
+func printEpisodes(episodes []search.ResultGroup, columns []resultColumn) {
+	printer := table.NewTablePrinter(columnHeaders([]string{"Episode", "File Name", "Size", "Sources", "URL"}, columns))
+	printer.SetMaxWidths(columnWidths([]int{-1, defaultColWidths[0], defaultColWidths[1], -1, defaultColWidths[2]}, columns))
+
+	for _, episode := range episodes {
+		label := episode.Release.EpisodeLabel()
+		if label == "" {
+			label = "--"
+		}
+
+		for _, g := range search.GroupResults(episode.Sources) {
+			merged := g.Merged()
+			printer.AddRow(columnValues(table.Row{label, g.Name, formatSize(g.Size), strconv.Itoa(len(g.Sources)), groupURLs(g)}, columns, &merged))
+		}
+	}
+	printer.Print()
+}
+
+func printResultStream(results <-chan search.XdccFileInfo, filter *search.ReleaseFilter, columns []resultColumn) {
+	printer := table.NewTablePrinter(columnHeaders([]string{"File Name", "Size", "URL"}, columns))
+	widths := append([]int{}, streamColWidths...)
+	for range columns {
+		widths = append(widths, streamExtraColWidth)
+	}
+	printer.SetMaxWidths(widths)
+
+	printer.PrintHeader()
+	for fileInfo := range results {
+		if !filter.Match(fileInfo.Release) {
+			continue
+		}
+		printer.PrintRow(columnValues(table.Row{fileInfo.Name, formatSize(fileInfo.Size), fileInfo.URL.String()}, columns, &fileInfo))
+	}
+	printer.PrintFooter()
+}
+
+func transferLoop(transfer xdcc.Transfer) error {
 	bar := pb.NewProgressBar()
+	var segmentBar pb.SegmentedProgressBar
 
 	evts := transfer.PollEvents()
-	quit := false
-	for !quit {
+	for {
 		e := <-evts
 		switch evtType := e.(type) {
 		case *xdcc.TransferStartedEvent:
@@ -91,12 +272,29 @@ func transferLoop(transfer xdcc.Transfer) {
 			bar.SetState(pb.ProgressStateDownloading)
 		case *xdcc.TransferProgessEvent:
 			bar.Increment(int(evtType.TransferBytes))
+		case *xdcc.TransferSegmentStartedEvent:
+			if segmentBar == nil {
+				segmentBar = pb.NewSegmentedProgressBar(evtType.Segments)
+			}
+			segmentBar.SetSegment(evtType.Segment, evtType.FileName, int(evtType.Size))
+		case *xdcc.TransferSegmentProgressEvent:
+			segmentBar.Increment(evtType.Segment, int(evtType.TransferBytes))
+		case *xdcc.TransferSegmentCompletedEvent:
+			segmentBar.SetState(evtType.Segment, pb.ProgressStateCompleted)
 		case *xdcc.TransferCompletedEvent:
 			bar.SetState(pb.ProgressStateCompleted)
-			quit = true
+			return nil
+		case *xdcc.TransferAbortedEvent:
+			bar.SetState(pb.ProgressStateAborted)
+			return errors.New(evtType.Error)
+		case *xdcc.TransferVerificationFailedEvent:
+			bar.SetState(pb.ProgressStateCorrupted)
+			return evtType
+		case *xdcc.TransferRestartedEvent:
+			bar.SetState(pb.ProgressStateCorrupted)
+			bar = pb.NewProgressBar()
 		}
 	}
-	// TODO: do clean-up operations here
 }
 
 func suggestUnknownAuthoritySwitch(err error) {
@@ -105,15 +303,15 @@ func suggestUnknownAuthoritySwitch(err error) {
 	}
 }
 
-func doTransfer(transfer xdcc.Transfer) {
+func doTransfer(transfer xdcc.Transfer) error {
 	err := transfer.Start()
 	if err != nil {
 		fmt.Println(err)
 		suggestUnknownAuthoritySwitch(err)
-		return
+		return err
 	}
 
-	transferLoop(transfer)
+	return transferLoop(transfer)
 }
 
 func parseFlags(flagSet *flag.FlagSet, args []string) []string {
@@ -134,6 +332,11 @@ func findFirstFlag(args []string) int {
 	return -1
 }
 
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func loadUrlListFile(filePath string) []string {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -158,17 +361,35 @@ func loadUrlListFile(filePath string) []string {
 }
 
 func printGetUsageAndExit(flagSet *flag.FlagSet) {
-	fmt.Printf("usage: get url1 url2 ... [-o path] [-i file] [--ssl-only]\n\nFlag set:\n")
+	fmt.Printf("usage: get url1 url2 ... [-o path] [-i file] [--ssl-only]\n\nmirrors of the same file can be passed as a single comma separated argument (url1,url2,...)\n\nFlag set:\n")
 	flagSet.PrintDefaults()
 	os.Exit(0)
 }
 
+// parseSources parses a comma separated list of urls referring to the same file.
+func parseSources(urlStr string) ([]xdcc.IRCFile, error) {
+	sources := make([]xdcc.IRCFile, 0)
+	for _, s := range strings.Split(urlStr, ",") {
+		url, err := xdcc.ParseURL(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *url)
+	}
+	return sources, nil
+}
+
 func execGet(args []string) {
 	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
-	path := getCmd.String("o", ".", "output folder of dowloaded file")
+	path := getCmd.String("o", userConfig.OutPath, "output folder of dowloaded file")
 	inputFile := getCmd.String("i", "", "input file containing a list of urls")
+	requestTimeout := getCmd.Duration("timeout", durationOr(userConfig.RequestTimeout, defaultRequestTimeout), "time to wait for a bot to start sending before switching to the next mirror")
+	queueTimeout := getCmd.Duration("queue-timeout", durationOr(userConfig.QueueTimeout, defaultQueueTimeout), "time to wait in the queue of a bot before switching to the next mirror")
+	segments := getCmd.Int("segments", 0, "download each file from up to this number of mirrors in parallel, each sending a different part")
+	redownloads := getCmd.Int("redownloads", 0, "number of times a file failing checksum verification is downloaded again")
+	preflight := getCmd.Bool("preflight", false, "query the bot with XDCC INFO before downloading, skipping unavailable packs")
 
-	sslOnly := getCmd.Bool("ssl-only", false, "force the client to use TSL connection")
+	sslOnly := getCmd.Bool("ssl-only", userConfig.SSLOnly, "force the client to use TSL connection")
 
 	urlList := parseFlags(getCmd, args)
 
@@ -180,9 +401,9 @@ func execGet(args []string) {
 		printGetUsageAndExit(getCmd)
 	}
 
-	wg := sync.WaitGroup{}
+	files := make([][]xdcc.IRCFile, 0, len(urlList))
 	for _, urlStr := range urlList {
-		url, err := xdcc.ParseURL(urlStr)
+		sources, err := parseSources(urlStr)
 		if errors.Is(err, xdcc.ErrInvalidURL) {
 			fmt.Printf("no valid irc url: %s\n", urlStr)
 			continue
@@ -192,25 +413,61 @@ func execGet(args []string) {
 			fmt.Println(err.Error())
 			os.Exit(1)
 		}
+		files = append(files, sources)
+	}
 
-		transfer := xdcc.NewTransfer(xdcc.Config{
-			File:    *url,
-			OutPath: *path,
-			SSLOnly: *sslOnly,
-		})
+	downloadFiles(files, xdcc.Config{
+		OutPath:        *path,
+		SSLOnly:        *sslOnly,
+		RequestTimeout: *requestTimeout,
+		QueueTimeout:   *queueTimeout,
+		Segments:       *segments,
+		Redownloads:    *redownloads,
+		Stats:          botStats,
+	}, *preflight)
+}
+
+// downloadFiles concurrently downloads each file from its list of sources,
+// using conf as a template for the transfer configuration.
+// If preflight is set, each pack is checked with XDCC INFO first.
+func downloadFiles(files [][]xdcc.IRCFile, conf xdcc.Config, preflight bool) {
+	wg := sync.WaitGroup{}
+	for _, sources := range files {
+		c := conf
+		c.File = sources[0]
+		c.Mirrors = sources[1:]
 
 		wg.Add(1)
-		go func(transfer xdcc.Transfer) {
-			doTransfer(transfer)
-			wg.Done()
-		}(transfer)
+		go func(c xdcc.Config) {
+			defer wg.Done()
+
+			if preflight {
+				if err := preflightCheck(c.File, &c); err != nil {
+					fmt.Printf("%s: %s\n", c.File.String(), err)
+					return
+				}
+			}
+			doTransfer(xdcc.NewTransfer(c))
+		}(c)
 	}
 	wg.Wait()
 }
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("one of the following subcommands is expected: [search, get]")
+		fmt.Println("one of the following subcommands is expected: [search, get, fetch, info, list, index, cache, bots, config]")
+		os.Exit(1)
+	}
+
+	// the location of the configuration file is needed to fix it when it cannot be loaded
+	if os.Args[1] == "config" && len(os.Args) == 3 && os.Args[2] == "path" {
+		execConfig(os.Args[2:])
+		return
+	}
+
+	userConfig = loadConfigOrExit()
+	if err := userConfig.Apply(); err != nil {
+		fmt.Printf("config: %s\n", err)
 		os.Exit(1)
 	}
 
@@ -219,6 +476,20 @@ func main() {
 		execSearch(os.Args[2:])
 	case "get":
 		execGet(os.Args[2:])
+	case "fetch":
+		execFetch(os.Args[2:])
+	case "info":
+		execInfo(os.Args[2:])
+	case "list":
+		execList(os.Args[2:])
+	case "index":
+		execIndex(os.Args[2:])
+	case "cache":
+		execCache(os.Args[2:])
+	case "bots":
+		execBots(os.Args[2:])
+	case "config":
+		execConfig(os.Args[2:])
 	default:
 		fmt.Println("no such command: ", os.Args[1])
 		os.Exit(1)
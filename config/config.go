@@ -0,0 +1,190 @@
+package config
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"xdcc-cli/search"
+	"xdcc-cli/xdcc"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the user settings read from the configuration file.
+// Global settings are the defaults of the flags of the subcommands, which take precedence over them.
+type Config struct {
+	// OutPath is the folder downloaded files are written to.
+	OutPath string `toml:"output"`
+
+	SSLOnly        bool          `toml:"ssl_only"`
+	RequestTimeout time.Duration `toml:"timeout"`
+	QueueTimeout   time.Duration `toml:"queue_timeout"`
+
+	// Nick, AltNicks, Username and Realname identify the user on all networks,
+	// unless overridden by a network section.
+	Nick     string   `toml:"nick,omitempty"`
+	AltNicks []string `toml:"alt_nicks,omitempty"`
+	Username string   `toml:"username,omitempty"`
+	Realname string   `toml:"realname,omitempty"`
+
+	// Networks holds the settings of specific IRC networks, keyed by network name.
+	Networks map[string]xdcc.NetworkConfig `toml:"networks"`
+
+	// Providers configures the search providers by name. Entries named after a built-in
+	// provider override its defaults, while other entries define new providers.
+	Providers map[string]search.ProviderConfig `toml:"providers"`
+
+	Cache CacheConfig `toml:"cache"`
+}
+
+// CacheConfig configures the on-disk cache of search results.
+type CacheConfig struct {
+	Disabled bool          `toml:"disabled"`
+	TTL      time.Duration `toml:"ttl"`
+}
+
+// DefaultPath returns the location of the configuration file, following the XDG base directory specification.
+func DefaultPath() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, _ := os.UserHomeDir()
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "xdcc-cli", "config.toml")
+}
+
+// Default returns the configuration used when no configuration file exists.
+func Default() *Config {
+	return &Config{
+		OutPath:   ".",
+		Networks:  make(map[string]xdcc.NetworkConfig),
+		Providers: search.DefaultProviderConfigs(),
+		Cache:     CacheConfig{TTL: search.DefaultCacheTTL},
+	}
+}
+
+// Load reads the configuration file at path, on top of the default configuration.
+// A missing file is not an error.
+func Load(path string) (*Config, error) {
+	conf := Default()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileConf := &Config{}
+	if _, err := toml.Decode(string(data), fileConf); err != nil {
+		return nil, err
+	}
+
+	if fileConf.OutPath != "" {
+		conf.OutPath = expandHome(fileConf.OutPath)
+	}
+	conf.SSLOnly = fileConf.SSLOnly
+	conf.RequestTimeout = fileConf.RequestTimeout
+	conf.QueueTimeout = fileConf.QueueTimeout
+	conf.Nick = fileConf.Nick
+	conf.AltNicks = fileConf.AltNicks
+	conf.Username = fileConf.Username
+	conf.Realname = fileConf.Realname
+
+	for name, network := range fileConf.Networks {
+		network.CertFile = expandHome(network.CertFile)
+		network.KeyFile = expandHome(network.KeyFile)
+		network.CAFile = expandHome(network.CAFile)
+		conf.Networks[name] = network
+	}
+
+	conf.Cache.Disabled = fileConf.Cache.Disabled
+	if fileConf.Cache.TTL > 0 {
+		conf.Cache.TTL = fileConf.Cache.TTL
+	}
+
+	for name, provider := range fileConf.Providers {
+		if defaults, ok := conf.Providers[name]; ok {
+			provider = mergeProviderConfig(defaults, provider)
+		}
+		conf.Providers[name] = provider
+	}
+	return conf, nil
+}
+
+// expandHome replaces a leading "~" in path with the home directory of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// NetworkDefaults returns the settings shared by all networks.
+func (conf *Config) NetworkDefaults() xdcc.NetworkConfig {
+	return xdcc.NetworkConfig{
+		Nick:     conf.Nick,
+		AltNicks: conf.AltNicks,
+		Username: conf.Username,
+		Realname: conf.Realname,
+	}
+}
+
+// Apply makes the network settings effective for all the connections of the process.
+func (conf *Config) Apply() error {
+	return xdcc.SetNetworkConfigs(conf.NetworkDefaults(), conf.Networks)
+}
+
+// Encode writes the configuration in TOML format, hiding passwords.
+func (conf *Config) Encode(w io.Writer) error {
+	c := *conf
+	c.Networks = make(map[string]xdcc.NetworkConfig, len(conf.Networks))
+	for name, network := range conf.Networks {
+		if network.Password != "" {
+			network.Password = hiddenPassword
+		}
+		if network.ServerPassword != "" {
+			network.ServerPassword = hiddenPassword
+		}
+		c.Networks[name] = network
+	}
+	return toml.NewEncoder(w).Encode(&c)
+}
+
+const hiddenPassword = "********"
+
+// mergeProviderConfig fills the options left unset in conf with the ones of defaults.
+func mergeProviderConfig(defaults search.ProviderConfig, conf search.ProviderConfig) search.ProviderConfig {
+	if conf.Type == "" {
+		conf.Type = defaults.Type
+	}
+	if conf.BaseURL == "" {
+		conf.BaseURL = defaults.BaseURL
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = defaults.Timeout
+	}
+	if conf.MaxPages == 0 {
+		conf.MaxPages = defaults.MaxPages
+	}
+	if conf.Network == "" {
+		conf.Network = defaults.Network
+	}
+	if conf.Channel == "" {
+		conf.Channel = defaults.Channel
+	}
+	if conf.Weight == 0 {
+		conf.Weight = defaults.Weight
+	}
+	return conf
+}
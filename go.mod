@@ -3,7 +3,9 @@ module xdcc-cli
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/PuerkitoBio/goquery v1.8.0
 	github.com/fluffle/goirc v1.1.1
 	github.com/vbauerster/mpb/v7 v7.1.5
+	golang.org/x/net v0.0.0-20210916014120-12bc252f5db8
 )
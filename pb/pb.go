@@ -1,6 +1,7 @@
 package pb
 
 import (
+	"fmt"
 	"time"
 	"xdcc-cli/util"
 
@@ -15,6 +16,7 @@ const (
 	ProgressStateDownloading ProgressState = "downloading"
 	ProgressStateCompleted   ProgressState = "done"
 	ProgressStateAborted     ProgressState = "aborted"
+	ProgressStateCorrupted   ProgressState = "corrupted"
 )
 
 type ProgressBar interface {
@@ -117,3 +119,37 @@ func (bar *progressBarImpl) SetState(state ProgressState) {
 func NewProgressBar() ProgressBar {
 	return newProgressBarImpl()
 }
+
+// SegmentedProgressBar tracks the progress of each segment of a file downloaded in parallel.
+type SegmentedProgressBar interface {
+	SetSegment(segment int, fileName string, total int)
+	Increment(segment int, n int)
+	SetState(segment int, state ProgressState)
+}
+
+type segmentedProgressBarImpl struct {
+	bars []*progressBarImpl
+}
+
+func (bar *segmentedProgressBarImpl) SetSegment(segment int, fileName string, total int) {
+	b := bar.bars[segment]
+	b.SetFileName(fmt.Sprintf("[%d/%d] %s", segment+1, len(bar.bars), fileName))
+	b.SetTotal(total)
+	b.SetState(ProgressStateDownloading)
+}
+
+func (bar *segmentedProgressBarImpl) Increment(segment int, n int) {
+	bar.bars[segment].Increment(n)
+}
+
+func (bar *segmentedProgressBarImpl) SetState(segment int, state ProgressState) {
+	bar.bars[segment].SetState(state)
+}
+
+func NewSegmentedProgressBar(segments int) SegmentedProgressBar {
+	bars := make([]*progressBarImpl, segments)
+	for i := range bars {
+		bars[i] = newProgressBarImpl()
+	}
+	return &segmentedProgressBarImpl{bars: bars}
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xdcc-cli/util"
+	"xdcc-cli/xdcc"
+)
+
+const (
+	botSearchProviderName   = "xdcc search"
+	defaultBotSearchTimeout = 30 * time.Second
+)
+
+// BotSearchProvider searches files by sending XDCC SEARCH requests directly to a set of bots.
+type BotSearchProvider struct {
+	Bots    []xdcc.IRCBot
+	SSLOnly bool
+	Timeout time.Duration
+}
+
+// matches iroffer search replies, e.g. ` - Pack #5 matches, "file.mkv"`
+var botSearchReplyRegex = regexp.MustCompile(`Pack #(\d+) matches, "(.+)"(.*)$`)
+
+var botSearchSizeRegex = regexp.MustCompile(`[\[(]\s*<?([\d.]+\s*[KMGT]i?B?)\s*[\])]`)
+
+// ParseBotSearchReply parses the reply of a bot to an XDCC SEARCH request.
+// Both iroffer search reply lines and packlist lines are recognized.
+func ParseBotSearchReply(bot xdcc.IRCBot, lines []string) []XdccFileInfo {
+	fileInfos := ParsePacklist(bot, lines)
+	for i := range fileInfos {
+		fileInfos[i].Provider = botSearchProviderName
+	}
+
+	for _, line := range lines {
+		matches := botSearchReplyRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		slot, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+
+		info := XdccFileInfo{
+			URL:      bot.GetFile(slot),
+			Name:     matches[2],
+			Size:     -1,
+			Slot:     slot,
+			Provider: botSearchProviderName,
+		}
+
+		if sizeMatches := botSearchSizeRegex.FindStringSubmatch(matches[3]); sizeMatches != nil {
+			size := strings.ReplaceAll(strings.ReplaceAll(sizeMatches[1], " ", ""), "i", "")
+			if parsed, err := parsePacklistSize(size); err == nil {
+				info.Size = parsed
+			}
+		}
+		fileInfos = append(fileInfos, info)
+	}
+	return fileInfos
+}
+
+// botsByNetwork groups bots by network, so that a single connection is made to each network.
+func botsByNetwork(bots []xdcc.IRCBot) map[string][]xdcc.IRCBot {
+	groups := make(map[string][]xdcc.IRCBot)
+	for _, bot := range bots {
+		network := strings.ToLower(bot.Network)
+		groups[network] = append(groups[network], bot)
+	}
+	return groups
+}
+
+func (p *BotSearchProvider) searchNetwork(bots []xdcc.IRCBot, keywords []string) ([]XdccFileInfo, error) {
+	channels := make([]string, 0)
+	targets := make([]string, 0)
+	botsByName := make(map[string]xdcc.IRCBot)
+	for _, bot := range bots {
+		if !util.ContainsFold(channels, bot.Channel) {
+			channels = append(channels, bot.Channel)
+		}
+		targets = append(targets, bot.Name)
+		botsByName[strings.ToLower(bot.Name)] = bot
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultBotSearchTimeout
+	}
+
+	replies, err := xdcc.Query(xdcc.QueryConfig{
+		Network:  bots[0].Network,
+		SSLOnly:  p.SSLOnly,
+		Channels: channels,
+		Targets:  targets,
+		Timeout:  timeout,
+	}, &xdcc.XdccSearchReq{Terms: strings.Join(keywords, " ")})
+	if err != nil {
+		return nil, err
+	}
+
+	linesByBot := make(map[string][]string)
+	for _, r := range replies {
+		nick := strings.ToLower(r.Nick)
+		linesByBot[nick] = append(linesByBot[nick], r.Text)
+	}
+
+	fileInfos := make([]XdccFileInfo, 0)
+	for nick, lines := range linesByBot {
+		fileInfos = append(fileInfos, ParseBotSearchReply(botsByName[nick], lines)...)
+	}
+	return fileInfos, nil
+}
+
+func (p *BotSearchProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	mtx := sync.Mutex{}
+	fileInfos := make([]XdccFileInfo, 0)
+
+	wg := sync.WaitGroup{}
+	for _, bots := range botsByNetwork(p.Bots) {
+		wg.Add(1)
+		go func(bots []xdcc.IRCBot) {
+			defer wg.Done()
+
+			res, err := p.searchNetwork(bots, keywords)
+			if err != nil {
+				return
+			}
+
+			mtx.Lock()
+			fileInfos = append(fileInfos, res...)
+			mtx.Unlock()
+		}(bots)
+	}
+	wg.Wait()
+
+	return fileInfos, nil
+}
@@ -0,0 +1,282 @@
+package search
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheTTL  = time.Hour
+	cacheStatsFile   = "stats.json"
+	cacheEntrySuffix = ".json"
+	cacheDirName     = "search"
+	cacheDirMode     = 0755
+	cacheFileMode    = 0644
+)
+
+// DefaultCacheDir returns the location of the search cache, under $XDG_CACHE_HOME.
+func DefaultCacheDir() string {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, _ := os.UserHomeDir()
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "xdcc-cli", cacheDirName)
+}
+
+type cacheEntry struct {
+	Provider string
+	Query    string
+	Limit    int
+	Created  time.Time
+	Results  []XdccFileInfo
+}
+
+// CacheStats describes the content and the usage of a result cache.
+type CacheStats struct {
+	Hits    int64
+	Misses  int64
+	Entries int   `json:"-"`
+	Expired int   `json:"-"`
+	Size    int64 `json:"-"`
+}
+
+// ResultCache stores search results on disk, one file per provider and query,
+// for TTL after they have been retrieved.
+type ResultCache struct {
+	Dir string
+	TTL time.Duration
+
+	// Refresh disables cache lookups, while still storing new results.
+	Refresh bool
+
+	mtx    sync.Mutex
+	hits   int64
+	misses int64
+}
+
+func NewResultCache(dir string, ttl time.Duration) *ResultCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &ResultCache{Dir: dir, TTL: ttl}
+}
+
+// normalizeQuery makes queries differing only by case, spacing or keyword order share their results.
+func normalizeQuery(keywords []string) string {
+	tokens := strings.Fields(strings.ToLower(strings.Join(keywords, " ")))
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+func (c *ResultCache) entryPath(provider string, query string, limit int) string {
+	sum := sha1.Sum([]byte(provider + "\x00" + query + "\x00" + strconv.Itoa(limit)))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+cacheEntrySuffix)
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{}
+	err = json.Unmarshal(data, entry)
+	return entry, err
+}
+
+func (c *ResultCache) expired(entry *cacheEntry) bool {
+	return time.Since(entry.Created) > c.TTL
+}
+
+// Get returns the cached results of a query to provider, if they have not expired yet.
+func (c *ResultCache) Get(provider string, keywords []string, limit int) ([]XdccFileInfo, bool) {
+	query := normalizeQuery(keywords)
+
+	var entry *cacheEntry
+	if !c.Refresh {
+		entry, _ = readCacheEntry(c.entryPath(provider, query, limit))
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if entry == nil || entry.Provider != provider || entry.Query != query || c.expired(entry) {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return entry.Results, true
+}
+
+// Put stores the results of a query to provider.
+func (c *ResultCache) Put(provider string, keywords []string, limit int, results []XdccFileInfo) error {
+	if err := os.MkdirAll(c.Dir, cacheDirMode); err != nil {
+		return err
+	}
+
+	query := normalizeQuery(keywords)
+	data, err := json.Marshal(&cacheEntry{
+		Provider: provider,
+		Query:    query,
+		Limit:    limit,
+		Created:  time.Now(),
+		Results:  results,
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.entryPath(provider, query, limit), data, cacheFileMode)
+}
+
+// Stats returns the content of the cache together with the number of hits and misses
+// recorded so far, including the ones not yet saved through SaveStats.
+func (c *ResultCache) Stats() (*CacheStats, error) {
+	stats := &CacheStats{}
+	if data, err := ioutil.ReadFile(filepath.Join(c.Dir, cacheStatsFile)); err == nil {
+		json.Unmarshal(data, stats)
+	}
+
+	c.mtx.Lock()
+	stats.Hits += c.hits
+	stats.Misses += c.misses
+	c.mtx.Unlock()
+
+	files, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.Name() == cacheStatsFile || !strings.HasSuffix(f.Name(), cacheEntrySuffix) {
+			continue
+		}
+
+		stats.Entries++
+		stats.Size += f.Size()
+		if entry, err := readCacheEntry(filepath.Join(c.Dir, f.Name())); err != nil || c.expired(entry) {
+			stats.Expired++
+		}
+	}
+	return stats, nil
+}
+
+// SaveStats adds the hits and misses recorded since the last call to the ones stored on disk.
+func (c *ResultCache) SaveStats() error {
+	stats, err := c.Stats()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.Dir, cacheDirMode); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(c.Dir, cacheStatsFile), data, cacheFileMode); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.hits, c.misses = 0, 0
+	c.mtx.Unlock()
+	return nil
+}
+
+// Prune removes the expired entries, or all of them, along with the stats, when all is set.
+// It returns the number of removed entries.
+func (c *ResultCache) Prune(all bool) (int, error) {
+	files, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, f := range files {
+		path := filepath.Join(c.Dir, f.Name())
+		if f.Name() == cacheStatsFile {
+			if all {
+				os.Remove(path)
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(f.Name(), cacheEntrySuffix) {
+			continue
+		}
+
+		if entry, err := readCacheEntry(path); all || err != nil || c.expired(entry) {
+			if err := os.Remove(path); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// CachedProvider decorates a provider, storing its results in a cache and
+// answering repeated queries from it.
+type CachedProvider struct {
+	Name     string
+	Provider XdccSearchProvider
+	Cache    *ResultCache
+}
+
+func (p *CachedProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	return collectPages(p, keywords, 0)
+}
+
+func (p *CachedProvider) SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error {
+	if results, ok := p.Cache.Get(p.Name, keywords, limit); ok {
+		onPage(results)
+		return nil
+	}
+
+	results := make([]XdccFileInfo, 0)
+	if paged, ok := p.Provider.(PagedSearchProvider); ok {
+		err := paged.SearchPages(keywords, limit, func(page []XdccFileInfo) {
+			results = append(results, page...)
+			onPage(page)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		resList, err := p.Provider.Search(keywords)
+		if err != nil {
+			return err
+		}
+
+		if limit > 0 && len(resList) > limit {
+			resList = resList[:limit]
+		}
+		results = resList
+		onPage(results)
+	}
+
+	// a failure to store the results does not affect the search
+	p.Cache.Put(p.Name, keywords, limit, results)
+	return nil
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"strings"
+	"xdcc-cli/xdcc"
+)
+
+// ResultGroup is a file offered by several sources: the results sharing the same
+// name and size. The embedded result is the first one of the group.
+type ResultGroup struct {
+	XdccFileInfo
+	Sources []XdccFileInfo
+}
+
+// URLs returns the urls of all the sources of the group, starting from the first one.
+func (g *ResultGroup) URLs() []xdcc.IRCFile {
+	urls := make([]xdcc.IRCFile, 0, len(g.Sources))
+	for _, s := range g.Sources {
+		urls = append(urls, s.URL)
+	}
+	return urls
+}
+
+// Merged returns the first result of the group, with the metadata of all its sources combined:
+// the total number of downloads, the best bot speed and the most recent time the file has been seen.
+func (g *ResultGroup) Merged() XdccFileInfo {
+	merged := g.XdccFileInfo
+	merged.Gets = 0
+	for _, s := range g.Sources {
+		merged.Gets += s.Gets
+		if s.BotSpeed > merged.BotSpeed {
+			merged.BotSpeed = s.BotSpeed
+		}
+		if s.LastSeen != nil && (merged.LastSeen == nil || s.LastSeen.After(*merged.LastSeen)) {
+			merged.LastSeen = s.LastSeen
+		}
+	}
+	return merged
+}
+
+// NormalizeName makes names differing only by case or separators (e.g. "Foo.Bar.mkv" and "foo_bar.mkv") equal.
+func NormalizeName(name string) string {
+	return strings.Join(tokenize(name), " ")
+}
+
+type groupKey struct {
+	name    string
+	size    int64
+	season  int
+	episode int
+}
+
+// GroupResults groups results by name and size, keeping the groups in the order of their first result.
+// Names are compared exactly, so that the sources of a group can be used as mirrors by transfers,
+// which resume and merge files by name.
+func GroupResults(results []XdccFileInfo) []ResultGroup {
+	return groupResults(results, func(res XdccFileInfo) groupKey {
+		return groupKey{name: res.Name, size: res.Size}
+	})
+}
+
+// GroupResultsByEpisode groups the releases of the same episode of a show, regardless
+// of their group, resolution or encoding. Results not having an episode are grouped as by GroupResults.
+func GroupResultsByEpisode(results []XdccFileInfo) []ResultGroup {
+	return groupResults(results, func(res XdccFileInfo) groupKey {
+		if res.Release.Episode == 0 || res.Release.Title == "" {
+			return groupKey{name: res.Name, size: res.Size}
+		}
+		return groupKey{
+			name:    NormalizeName(res.Release.Title),
+			size:    -1,
+			season:  res.Release.Season,
+			episode: res.Release.Episode,
+		}
+	})
+}
+
+func groupResults(results []XdccFileInfo, keyOf func(XdccFileInfo) groupKey) []ResultGroup {
+	groups := make([]ResultGroup, 0)
+	indices := make(map[groupKey]int)
+	for _, res := range results {
+		key := keyOf(res)
+		if i, ok := indices[key]; ok {
+			groups[i].Sources = append(groups[i].Sources, res)
+			continue
+		}
+
+		indices[key] = len(groups)
+		groups = append(groups, ResultGroup{
+			XdccFileInfo: res,
+			Sources:      []XdccFileInfo{res},
+		})
+	}
+	return groups
+}
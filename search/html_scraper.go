@@ -0,0 +1,152 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"xdcc-cli/xdcc"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	htmlScraperProviderType = "html"
+	htmlScraperQueryParam   = "{query}"
+)
+
+// HTMLScraperConfig describes the structure of a search results page through CSS selectors.
+// Field selectors are relative to a row. The location of a file is either given by the URL
+// selector, matching an element whose href attribute (or text) is an irc://network/channel/bot/slot url,
+// or by the Network, Channel, Bot and Slot selectors.
+type HTMLScraperConfig struct {
+	Rows    string `toml:"rows,omitempty"`
+	Name    string `toml:"name,omitempty"`
+	Size    string `toml:"size,omitempty"`
+	URL     string `toml:"url,omitempty"`
+	Network string `toml:"network,omitempty"`
+	Channel string `toml:"channel,omitempty"`
+	Bot     string `toml:"bot,omitempty"`
+	Slot    string `toml:"slot,omitempty"`
+}
+
+// HTMLScraperProvider searches files on index sites not supported natively, by extracting
+// the results from the HTML page returned by BaseURL, where "{query}" is replaced by the keywords.
+type HTMLScraperProvider struct {
+	Name    string
+	BaseURL string
+	Timeout time.Duration
+	Scraper HTMLScraperConfig
+}
+
+var ErrInvalidScraperConfig = errors.New("invalid html scraper configuration")
+
+func NewHTMLScraperProvider(conf ProviderConfig) (*HTMLScraperProvider, error) {
+	s := conf.Scraper
+	if conf.BaseURL == "" || s.Rows == "" || s.Name == "" {
+		return nil, fmt.Errorf("%w: base_url, rows and name are required", ErrInvalidScraperConfig)
+	}
+
+	if s.URL == "" && (s.Network == "" || s.Channel == "" || s.Bot == "" || s.Slot == "") {
+		return nil, fmt.Errorf("%w: either url or network, channel, bot and slot are required", ErrInvalidScraperConfig)
+	}
+
+	name := conf.Name
+	if name == "" {
+		name = htmlScraperProviderType
+	}
+
+	return &HTMLScraperProvider{
+		Name:    name,
+		BaseURL: conf.BaseURL,
+		Timeout: conf.Timeout,
+		Scraper: s,
+	}, nil
+}
+
+func selectText(s *goquery.Selection, selector string) string {
+	if selector == "" {
+		return ""
+	}
+	return strings.TrimSpace(s.Find(selector).First().Text())
+}
+
+func (p *HTMLScraperProvider) parseRow(row *goquery.Selection) (*XdccFileInfo, error) {
+	info := &XdccFileInfo{
+		Name:     selectText(row, p.Scraper.Name),
+		Size:     -1,
+		Provider: p.Name,
+	}
+
+	if p.Scraper.URL != "" {
+		sel := row.Find(p.Scraper.URL).First()
+		urlStr, ok := sel.Attr("href")
+		if !ok {
+			urlStr = strings.TrimSpace(sel.Text())
+		}
+
+		fileURL, err := xdcc.ParseURL(urlStr)
+		if err != nil {
+			return nil, err
+		}
+		info.URL = *fileURL
+	} else {
+		slot, err := strconv.Atoi(strings.TrimPrefix(selectText(row, p.Scraper.Slot), "#"))
+		if err != nil {
+			return nil, err
+		}
+
+		channel := selectText(row, p.Scraper.Channel)
+		if !strings.HasPrefix(channel, "#") {
+			channel = "#" + channel
+		}
+
+		info.URL = xdcc.IRCFile{
+			Network:  selectText(row, p.Scraper.Network),
+			Channel:  channel,
+			UserName: selectText(row, p.Scraper.Bot),
+			Slot:     slot,
+		}
+	}
+	info.Slot = info.URL.Slot
+
+	if info.Name == "" || info.URL.Network == "" || info.URL.UserName == "" {
+		return nil, errors.New("incomplete row")
+	}
+
+	if size, err := parsePacklistSize(strings.ReplaceAll(selectText(row, p.Scraper.Size), " ", "")); err == nil {
+		info.Size = size
+	}
+	return info, nil
+}
+
+func (p *HTMLScraperProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	query := url.QueryEscape(strings.Join(keywords, " "))
+
+	client := &http.Client{Timeout: p.Timeout}
+	res, err := client.Get(strings.ReplaceAll(p.BaseURL, htmlScraperQueryParam, query))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfos := make([]XdccFileInfo, 0)
+	doc.Find(p.Scraper.Rows).Each(func(_ int, row *goquery.Selection) {
+		if info, err := p.parseRow(row); err == nil {
+			fileInfos = append(fileInfos, *info)
+		}
+	})
+	return fileInfos, nil
+}
@@ -0,0 +1,212 @@
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"xdcc-cli/util"
+	"xdcc-cli/xdcc"
+)
+
+const localIndexProviderName = "index"
+
+// IndexSource is a bot whose packlist is stored in the local index. The packlist is
+// downloaded from PacklistURL, when set, or retrieved from the bot through XDCC LIST.
+type IndexSource struct {
+	Bot         xdcc.IRCBot
+	PacklistURL string `json:",omitempty"`
+}
+
+// Index is an on-disk collection of bot packlists, which can be searched offline.
+type Index struct {
+	Sources []IndexSource
+	Files   []XdccFileInfo
+	Updated time.Time
+}
+
+// DefaultIndexPath returns the location of the index, under $XDG_DATA_HOME.
+func DefaultIndexPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "xdcc-cli", "index.json")
+}
+
+// LoadIndex reads the index stored at path, returning an empty index if it does not exist.
+func LoadIndex(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	idx := &Index{}
+	err = json.NewDecoder(bufio.NewReader(file)).Decode(idx)
+	return idx, err
+}
+
+func (idx *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// RemoveSource removes the source of bot from the index, together with its files.
+func (idx *Index) RemoveSource(bot xdcc.IRCBot) {
+	sources := make([]IndexSource, 0, len(idx.Sources))
+	for _, s := range idx.Sources {
+		if s.Bot != bot {
+			sources = append(sources, s)
+		}
+	}
+	idx.Sources = sources
+
+	files := make([]XdccFileInfo, 0, len(idx.Files))
+	for _, file := range idx.Files {
+		if file.URL.GetBot() != bot {
+			files = append(files, file)
+		}
+	}
+	idx.Files = files
+}
+
+// AddSource adds a source to the index, replacing any source for the same bot.
+func (idx *Index) AddSource(src IndexSource) {
+	for i, s := range idx.Sources {
+		if s.Bot == src.Bot {
+			idx.Sources[i] = src
+			return
+		}
+	}
+	idx.Sources = append(idx.Sources, src)
+}
+
+func fetchPacklist(url string) ([]string, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		lines = append(lines, util.StripIRCFormatting(scanner.Text()))
+	}
+	return lines, scanner.Err()
+}
+
+func (src *IndexSource) fetch(sslOnly bool) ([]XdccFileInfo, error) {
+	var lines []string
+	var err error
+	if src.PacklistURL != "" {
+		lines, err = fetchPacklist(src.PacklistURL)
+	} else {
+		lines, err = xdcc.ListPacks(src.Bot, sslOnly)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfos := ParsePacklist(src.Bot, lines)
+	for i := range fileInfos {
+		fileInfos[i].Provider = localIndexProviderName
+	}
+	return fileInfos, nil
+}
+
+// Update refreshes the packlists of all the sources. The files of a source which cannot be
+// fetched are kept as they are, and the corresponding error is returned in the map.
+func (idx *Index) Update(sslOnly bool) map[xdcc.IRCBot]error {
+	errs := make(map[xdcc.IRCBot]error)
+
+	filesByBot := make(map[xdcc.IRCBot][]XdccFileInfo)
+	for _, file := range idx.Files {
+		bot := file.URL.GetBot()
+		filesByBot[bot] = append(filesByBot[bot], file)
+	}
+
+	files := make([]XdccFileInfo, 0)
+	for _, src := range idx.Sources {
+		fileInfos, err := src.fetch(sslOnly)
+		if err != nil {
+			errs[src.Bot] = err
+			fileInfos = filesByBot[src.Bot]
+		}
+		files = append(files, fileInfos...)
+	}
+
+	idx.Files = files
+	idx.Updated = time.Now()
+	return errs
+}
+
+// MatchKeywords reports whether each keyword matches a token of name,
+// either as a prefix or with at most one typo.
+func MatchKeywords(keywords []string, name string) bool {
+	nameTokens := tokenize(name)
+	for _, keyword := range tokenize(strings.Join(keywords, " ")) {
+		if !matchToken(keyword, nameTokens) {
+			return false
+		}
+	}
+	return true
+}
+
+const minFuzzyTokenLength = 4
+
+func matchToken(keyword string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.HasPrefix(token, keyword) {
+			return true
+		}
+
+		if len(keyword) >= minFuzzyTokenLength && util.EditDistance(keyword, token) <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalIndexProvider searches the files stored in the local index.
+type LocalIndexProvider struct {
+	Path string
+}
+
+func (p *LocalIndexProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	idx, err := LoadIndex(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfos := make([]XdccFileInfo, 0)
+	for _, file := range idx.Files {
+		if MatchKeywords(keywords, file.Name) {
+			fileInfos = append(fileInfos, file)
+		}
+	}
+	return fileInfos, nil
+}
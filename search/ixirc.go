@@ -0,0 +1,133 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"xdcc-cli/xdcc"
+)
+
+const (
+	ixIRCURL             = "https://ixirc.com/api/"
+	ixIRCProviderName    = "ixirc"
+	ixIRCDefaultMaxPages = 5
+)
+
+// IxIRCProvider searches files through the JSON API of ixirc.com,
+// following the result pages up to MaxPages.
+type IxIRCProvider struct {
+	BaseURL  string
+	Timeout  time.Duration
+	MaxPages int
+}
+
+type IxIRCResult struct {
+	Name        string `json:"name"`
+	NetworkName string `json:"nname"`
+	NetworkAddr string `json:"naddr"`
+	Channel     string `json:"cname"`
+	Bot         string `json:"uname"`
+	Pack        int    `json:"n"`
+	Gets        int    `json:"gets"`
+	Size        int64  `json:"sz"`
+
+	// LastSeen is the unix time the pack was last seen.
+	LastSeen int64 `json:"last"`
+}
+
+type IxIRCResponse struct {
+	Count     int           `json:"c"`
+	PageCount int           `json:"pc"`
+	Page      int           `json:"pn"`
+	Results   []IxIRCResult `json:"results"`
+}
+
+func (p *IxIRCProvider) parseResult(res *IxIRCResult) (*XdccFileInfo, error) {
+	network := res.NetworkAddr
+	if network == "" {
+		network = res.NetworkName
+	}
+
+	if network == "" || res.Bot == "" || res.Channel == "" {
+		return nil, fmt.Errorf("incomplete result")
+	}
+
+	channel := res.Channel
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	info := &XdccFileInfo{
+		URL: xdcc.IRCFile{
+			Network:  network,
+			Channel:  channel,
+			UserName: res.Bot,
+			Slot:     res.Pack,
+		},
+		Name:     res.Name,
+		Size:     res.Size,
+		Slot:     res.Pack,
+		Gets:     res.Gets,
+		Provider: ixIRCProviderName,
+	}
+
+	if res.LastSeen > 0 {
+		lastSeen := time.Unix(res.LastSeen, 0)
+		info.LastSeen = &lastSeen
+	}
+	return info, nil
+}
+
+func (p *IxIRCProvider) fetchPage(client *http.Client, query string, page int) (*IxIRCResponse, error) {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = ixIRCURL
+	}
+
+	res, err := client.Get(baseURL + "?q=" + query + "&pn=" + strconv.Itoa(page))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+
+	resp := &IxIRCResponse{}
+	err = json.NewDecoder(res.Body).Decode(resp)
+	return resp, err
+}
+
+func (p *IxIRCProvider) SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error {
+	query := url.QueryEscape(strings.Join(keywords, " "))
+
+	maxPages := p.MaxPages
+	if maxPages <= 0 && limit <= 0 {
+		maxPages = ixIRCDefaultMaxPages
+	}
+
+	client := &http.Client{Timeout: p.Timeout}
+	return fetchPages(limit, maxPages, func(page int) ([]XdccFileInfo, bool, error) {
+		resp, err := p.fetchPage(client, query, page)
+		if err != nil {
+			return nil, false, err
+		}
+
+		fileInfos := make([]XdccFileInfo, 0, len(resp.Results))
+		for i := range resp.Results {
+			if info, err := p.parseResult(&resp.Results[i]); err == nil {
+				fileInfos = append(fileInfos, *info)
+			}
+		}
+		return fileInfos, page+1 < resp.PageCount, nil
+	}, onPage)
+}
+
+func (p *IxIRCProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	return collectPages(p, keywords, 0)
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"xdcc-cli/xdcc"
+)
+
+const (
+	niblURL             = "https://api.nibl.co.uk/nibl"
+	niblNetwork         = "irc.rizon.net"
+	niblChannel         = "#nibl"
+	niblProviderName    = "nibl"
+	niblPageSize        = 100
+	niblDefaultMaxPages = 5
+)
+
+// NiblProvider searches files through nibl-style packlist APIs, which index the packs
+// of the bots of a single channel and identify bots by a numeric id.
+type NiblProvider struct {
+	Name     string
+	BaseURL  string
+	Network  string
+	Channel  string
+	Timeout  time.Duration
+	MaxPages int
+}
+
+type NiblBot struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type NiblPack struct {
+	BotID  int    `json:"botId"`
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	Size   string `json:"size"`
+}
+
+type niblBotsResponse struct {
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	Content []NiblBot `json:"content"`
+}
+
+type niblSearchResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Content []NiblPack `json:"content"`
+}
+
+func (p *NiblProvider) baseURL() string {
+	if p.BaseURL == "" {
+		return niblURL
+	}
+	return strings.TrimSuffix(p.BaseURL, "/")
+}
+
+func (p *NiblProvider) providerName() string {
+	if p.Name == "" {
+		return niblProviderName
+	}
+	return p.Name
+}
+
+func (p *NiblProvider) getJSON(client *http.Client, urlStr string, v interface{}) error {
+	res, err := client.Get(urlStr)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (p *NiblProvider) fetchBots(client *http.Client) (map[int]string, error) {
+	resp := &niblBotsResponse{}
+	if err := p.getJSON(client, p.baseURL()+"/bots", resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Status != "OK" {
+		return nil, fmt.Errorf("nibl: %s", resp.Message)
+	}
+
+	bots := make(map[int]string)
+	for _, bot := range resp.Content {
+		bots[bot.ID] = bot.Name
+	}
+	return bots, nil
+}
+
+func (p *NiblProvider) fetchPage(client *http.Client, query string, page int) ([]NiblPack, error) {
+	resp := &niblSearchResponse{}
+	urlStr := p.baseURL() + "/search?query=" + query + "&page=" + strconv.Itoa(page) + "&size=" + strconv.Itoa(niblPageSize)
+	if err := p.getJSON(client, urlStr, resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Status != "OK" {
+		return nil, fmt.Errorf("nibl: %s", resp.Message)
+	}
+	return resp.Content, nil
+}
+
+func (p *NiblProvider) parsePack(pack *NiblPack, bots map[int]string) (*XdccFileInfo, error) {
+	bot, ok := bots[pack.BotID]
+	if !ok {
+		return nil, fmt.Errorf("unknown bot id: %d", pack.BotID)
+	}
+
+	network := p.Network
+	if network == "" {
+		network = niblNetwork
+	}
+
+	channel := p.Channel
+	if channel == "" {
+		channel = niblChannel
+	}
+
+	size, err := parsePacklistSize(pack.Size)
+	if err != nil {
+		size = -1
+	}
+
+	return &XdccFileInfo{
+		URL: xdcc.IRCFile{
+			Network:  network,
+			Channel:  channel,
+			UserName: bot,
+			Slot:     pack.Number,
+		},
+		Name:     pack.Name,
+		Size:     size,
+		Slot:     pack.Number,
+		Provider: p.providerName(),
+	}, nil
+}
+
+func (p *NiblProvider) SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error {
+	query := url.QueryEscape(strings.Join(keywords, " "))
+
+	maxPages := p.MaxPages
+	if maxPages <= 0 && limit <= 0 {
+		maxPages = niblDefaultMaxPages
+	}
+
+	client := &http.Client{Timeout: p.Timeout}
+
+	bots, err := p.fetchBots(client)
+	if err != nil {
+		return err
+	}
+
+	return fetchPages(limit, maxPages, func(page int) ([]XdccFileInfo, bool, error) {
+		packs, err := p.fetchPage(client, query, page)
+		if err != nil {
+			return nil, false, err
+		}
+
+		fileInfos := make([]XdccFileInfo, 0, len(packs))
+		for i := range packs {
+			if info, err := p.parsePack(&packs[i], bots); err == nil {
+				fileInfos = append(fileInfos, *info)
+			}
+		}
+		return fileInfos, len(packs) == niblPageSize, nil
+	}, onPage)
+}
+
+func (p *NiblProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	return collectPages(p, keywords, 0)
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"xdcc-cli/xdcc"
+)
+
+const packlistProviderName = "packlist"
+
+// matches iroffer packlist lines, e.g. "#1   12x [350M] file.mkv"
+var packlistLineRegex = regexp.MustCompile(`^#(\d+)\s+(\d+)x\s+\[\s*([^\]]*?)\s*\]\s+(.+?)\s*$`)
+
+func parsePacklistSize(sizeStr string) (int64, error) {
+	sizeStr = strings.TrimLeft(sizeStr, "<>")
+	if len(sizeStr) > 1 && strings.HasSuffix(strings.ToUpper(sizeStr), "B") {
+		sizeStr = sizeStr[:len(sizeStr)-1]
+	}
+	return parseFileSize(strings.ToUpper(sizeStr))
+}
+
+// parseGets parses a download count, as in "12x", returning 0 when it is not valid.
+func parseGets(s string) int {
+	gets, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "x")))
+	if err != nil {
+		return 0
+	}
+	return gets
+}
+
+// parseSpeed parses a transfer rate, as in "1.5MB/s", into bytes per second, returning 0 when it is not valid.
+func parseSpeed(s string) int64 {
+	speed, err := parsePacklistSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	if err != nil {
+		return 0
+	}
+	return speed
+}
+
+// ParsePacklist parses the packlist of a bot, ignoring any line not describing a pack.
+func ParsePacklist(bot xdcc.IRCBot, lines []string) []XdccFileInfo {
+	fileInfos := make([]XdccFileInfo, 0)
+	for _, line := range lines {
+		matches := packlistLineRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			continue
+		}
+
+		slot, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+
+		info := XdccFileInfo{
+			URL:      bot.GetFile(slot),
+			Name:     matches[4],
+			Slot:     slot,
+			Provider: packlistProviderName,
+		}
+		info.Size, _ = parsePacklistSize(matches[3]) // ignoring error
+		info.Gets = parseGets(matches[2])
+
+		fileInfos = append(fileInfos, info)
+	}
+	return fileInfos
+}
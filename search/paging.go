@@ -0,0 +1,64 @@
+package search
+
+import "xdcc-cli/xdcc"
+
+// PagedSearchProvider is implemented by providers retrieving their results a page at a time.
+// SearchPages calls onPage with the results of each page as soon as it is retrieved, until limit
+// results have been found (no limit if not positive) or there are no more pages.
+type PagedSearchProvider interface {
+	XdccSearchProvider
+	SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error
+}
+
+// pageFetcher retrieves a page of results, also telling whether more pages follow.
+type pageFetcher func(page int) (results []XdccFileInfo, more bool, err error)
+
+// fetchPages retrieves pages of results, starting from the first one, until limit results have been
+// collected (when positive), maxPages pages have been retrieved (when positive), there are no more pages
+// or a page contains no new result, which happens when a site ignores the requested page.
+// Failing to retrieve a page other than the first one is not an error.
+func fetchPages(limit int, maxPages int, fetch pageFetcher, onPage func([]XdccFileInfo)) error {
+	seen := make(map[xdcc.IRCFile]bool)
+	count := 0
+	for page := 0; maxPages <= 0 || page < maxPages; page++ {
+		results, more, err := fetch(page)
+		if err != nil {
+			if page > 0 {
+				return nil
+			}
+			return err
+		}
+
+		newResults := make([]XdccFileInfo, 0, len(results))
+		for _, res := range results {
+			if limit > 0 && count == limit {
+				break
+			}
+
+			if !seen[res.URL] {
+				seen[res.URL] = true
+				newResults = append(newResults, res)
+				count++
+			}
+		}
+
+		if len(newResults) == 0 {
+			return nil
+		}
+		onPage(newResults)
+
+		if !more || (limit > 0 && count == limit) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// collectPages retrieves all the results of a paged provider at once.
+func collectPages(p PagedSearchProvider, keywords []string, limit int) ([]XdccFileInfo, error) {
+	fileInfos := make([]XdccFileInfo, 0)
+	err := p.SearchPages(keywords, limit, func(results []XdccFileInfo) {
+		fileInfos = append(fileInfos, results...)
+	})
+	return fileInfos, err
+}
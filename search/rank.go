@@ -0,0 +1,72 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Candidate is a search result together with its ranking score, in the range [0, 1].
+type Candidate struct {
+	XdccFileInfo
+	Score float64
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+const (
+	relevanceWeight   = 0.6
+	sizeWeight        = 0.25
+	botActivityWeight = 0.15
+)
+
+// RankCandidates orders results from the most to the least promising download. A result is preferred
+// when it is relevant to the search (see ScoreRelevance and ApplyBotReputations), when its size agrees
+// with the other results having the same name (so that fakes and truncated files are penalized)
+// and when it is offered by a bot which serves many other results.
+func RankCandidates(results []XdccFileInfo) []Candidate {
+	sizesByName := make(map[string]map[int64]int)
+	packsByBot := make(map[string]int)
+	maxPacks := 0
+	for _, res := range results {
+		name := strings.ToLower(res.Name)
+		if sizesByName[name] == nil {
+			sizesByName[name] = make(map[int64]int)
+		}
+		sizesByName[name][res.Size]++
+
+		bot := res.URL.Network + "/" + res.URL.UserName
+		packsByBot[bot]++
+		if packsByBot[bot] > maxPacks {
+			maxPacks = packsByBot[bot]
+		}
+	}
+
+	candidates := make([]Candidate, 0, len(results))
+	for _, res := range results {
+		sizes := sizesByName[strings.ToLower(res.Name)]
+		total := 0
+		for _, count := range sizes {
+			total += count
+		}
+		sizeScore := float64(sizes[res.Size]) / float64(total)
+
+		botScore := float64(packsByBot[res.URL.Network+"/"+res.URL.UserName]) / float64(maxPacks)
+
+		candidates = append(candidates, Candidate{
+			XdccFileInfo: res,
+			Score: relevanceWeight*res.Relevance +
+				sizeWeight*sizeScore +
+				botActivityWeight*botScore,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
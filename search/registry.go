@@ -0,0 +1,205 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProviderConfig configures a search provider. Which of the options are meaningful
+// depends on the provider type.
+type ProviderConfig struct {
+	// Name identifies the provider in the results. It is the key of the provider section.
+	Name string `toml:"-"`
+
+	Type     string        `toml:"type,omitempty"`
+	Disabled bool          `toml:"disabled,omitempty"`
+	BaseURL  string        `toml:"base_url,omitempty"`
+	Timeout  time.Duration `toml:"timeout,omitzero"`
+	Weight   float64       `toml:"weight,omitzero"`
+
+	// MaxPages limits the number of result pages retrieved from paginated APIs.
+	MaxPages int `toml:"max_pages,omitzero"`
+
+	// Network and Channel locate the bots indexed by "nibl" providers.
+	Network string `toml:"network,omitempty"`
+	Channel string `toml:"channel,omitempty"`
+
+	// Scraper describes how to extract results from the pages of "html" providers.
+	Scraper HTMLScraperConfig `toml:"scraper,omitempty"`
+}
+
+// ProviderFactory creates a provider from its configuration.
+type ProviderFactory func(conf ProviderConfig) (XdccSearchProvider, error)
+
+var providerFactories = map[string]ProviderFactory{
+	xdccEuProviderName: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return &XdccEuProvider{BaseURL: conf.BaseURL, Timeout: conf.Timeout, MaxPages: conf.MaxPages}, nil
+	},
+	sunXdccProviderName: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return &SunXdccProvider{BaseURL: conf.BaseURL, Timeout: conf.Timeout, MaxPages: conf.MaxPages}, nil
+	},
+	ixIRCProviderName: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return &IxIRCProvider{BaseURL: conf.BaseURL, Timeout: conf.Timeout, MaxPages: conf.MaxPages}, nil
+	},
+	niblProviderName: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return &NiblProvider{
+			Name:     conf.Name,
+			BaseURL:  conf.BaseURL,
+			Network:  conf.Network,
+			Channel:  conf.Channel,
+			Timeout:  conf.Timeout,
+			MaxPages: conf.MaxPages,
+		}, nil
+	},
+	htmlScraperProviderType: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return NewHTMLScraperProvider(conf)
+	},
+	localIndexProviderName: func(conf ProviderConfig) (XdccSearchProvider, error) {
+		return &LocalIndexProvider{Path: DefaultIndexPath()}, nil
+	},
+}
+
+// RegisterProvider makes a provider type available to configurations.
+func RegisterProvider(providerType string, factory ProviderFactory) {
+	providerFactories[providerType] = factory
+}
+
+var ErrUnknownProvider = errors.New("unknown provider type")
+
+// NewProvider creates a provider of the configured type.
+func NewProvider(conf ProviderConfig) (XdccSearchProvider, error) {
+	factory, ok := providerFactories[conf.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, conf.Type)
+	}
+	return factory(conf)
+}
+
+// DefaultProviderConfigs returns the configuration of the built-in providers, keyed by name.
+func DefaultProviderConfigs() map[string]ProviderConfig {
+	return map[string]ProviderConfig{
+		xdccEuProviderName:     {Type: xdccEuProviderName, Weight: 1},
+		sunXdccProviderName:    {Type: sunXdccProviderName, Weight: 1},
+		ixIRCProviderName:      {Type: ixIRCProviderName, Weight: 1},
+		niblProviderName:       {Type: niblProviderName, Weight: 1},
+		localIndexProviderName: {Type: localIndexProviderName, Weight: 1},
+	}
+}
+
+// NewProviderAggregatorFromConfig creates an aggregator of the enabled providers.
+// If names is not empty, only the providers listed in it are enabled.
+// The results of the providers are stored in cache, unless it is nil.
+func NewProviderAggregatorFromConfig(configs map[string]ProviderConfig, names []string, cache *ResultCache) (*ProviderAggregator, error) {
+	selected := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := configs[name]; !ok {
+			return nil, fmt.Errorf("no such provider: %s", name)
+		}
+		selected[name] = true
+	}
+
+	// providers are added in a deterministic order
+	sortedNames := make([]string, 0, len(configs))
+	for name := range configs {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	aggregator := NewProviderAggregator()
+	for _, name := range sortedNames {
+		conf := configs[name]
+		conf.Name = name
+		if (len(selected) > 0 && !selected[name]) || (len(selected) == 0 && conf.Disabled) {
+			continue
+		}
+
+		provider, err := NewProvider(conf)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+
+		// the local index is read from disk anyway, and is updated apart from the cache
+		if cache != nil && conf.Type != localIndexProviderName {
+			// results of the same provider at different addresses are cached apart
+			cacheName := name
+			if conf.BaseURL != "" {
+				cacheName += "@" + conf.BaseURL
+			}
+			provider = &CachedProvider{Name: cacheName, Provider: provider, Cache: cache}
+		}
+		weight := conf.Weight
+		if weight == 0 {
+			weight = defaultProviderWeight
+		}
+		aggregator.AddWeightedProvider(provider, weight)
+	}
+	return aggregator, nil
+}
+
+// ErrInvalidProviderSpec is returned for malformed provider selections.
+var ErrInvalidProviderSpec = errors.New("invalid provider option")
+
+// ParseProviderSpecs applies provider selections of the form "name" or "name:key=value,..."
+// (keys being base_url, timeout, weight and max_pages) to configs, returning the updated
+// configurations together with the selected names.
+func ParseProviderSpecs(configs map[string]ProviderConfig, specs []string) (map[string]ProviderConfig, []string, error) {
+	updated := make(map[string]ProviderConfig, len(configs))
+	for name, conf := range configs {
+		updated[name] = conf
+	}
+
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		name, options := spec, ""
+		if i := strings.Index(spec, ":"); i >= 0 {
+			name, options = spec[:i], spec[i+1:]
+		}
+
+		conf, ok := updated[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("no such provider: %s", name)
+		}
+
+		if options != "" {
+			for _, option := range strings.Split(options, ",") {
+				if err := setProviderOption(&conf, option); err != nil {
+					return nil, nil, fmt.Errorf("provider %s: %w", name, err)
+				}
+			}
+		}
+		updated[name] = conf
+		names = append(names, name)
+	}
+	return updated, names, nil
+}
+
+func setProviderOption(conf *ProviderConfig, option string) error {
+	kv := strings.SplitN(option, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("%w: %s", ErrInvalidProviderSpec, option)
+	}
+
+	var err error
+	key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+	switch key {
+	case "base_url":
+		conf.BaseURL = value
+	case "timeout":
+		conf.Timeout, err = time.ParseDuration(value)
+	case "weight":
+		conf.Weight, err = strconv.ParseFloat(value, 64)
+	case "max_pages":
+		conf.MaxPages, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidProviderSpec, key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProviderSpec, option)
+	}
+	return nil
+}
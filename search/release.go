@@ -0,0 +1,228 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"xdcc-cli/xdcc"
+)
+
+// Release describes the content of a file, as encoded in its name by scene and fansub naming conventions
+// (e.g. "Show.Name.S01E05.1080p.WEB-DL.x264-GROUP.mkv" or "[Group] Show Name - 05 (720p) [ABCD1234].mkv").
+// Fields not found in the name are left empty, or set to zero for Season and Episode.
+type Release struct {
+	Group      string `json:",omitempty"`
+	Title      string `json:",omitempty"`
+	Season     int    `json:",omitempty"`
+	Episode    int    `json:",omitempty"`
+	Resolution string `json:",omitempty"`
+	Codec      string `json:",omitempty"`
+	Source     string `json:",omitempty"`
+	CRC        string `json:",omitempty"`
+}
+
+var (
+	releaseExtRegex        = regexp.MustCompile(`\.[A-Za-z0-9]{2,4}$`)
+	releaseLeadingGroup    = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+	releaseTrailingGroup   = regexp.MustCompile(`-([A-Za-z0-9]+)$`)
+	releaseBracketsRegex   = regexp.MustCompile(`[\[(][^\])]*[\])]`)
+	releaseSeasonEpRegex   = regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,4})\b`)
+	releaseCrossEpRegex    = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	releaseEpisodeRegex    = regexp.MustCompile(`(?i)\b(?:E|Ep|Episode)[ ._]?(\d{1,4})\b`)
+	releaseAbsoluteEpRegex = regexp.MustCompile(`\s-\s(\d{1,4})(?:v\d)?(?:\s|$)`)
+	releaseResolutionRegex = regexp.MustCompile(`(?i)\b(?:(\d{3,4})[pi]|\d{3,4}x(\d{3,4})|(4k|uhd))\b`)
+	releaseYearRegex       = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+)
+
+// releaseCodecs and releaseSources map the lowercase spellings of tags to their canonical names.
+var (
+	releaseCodecs = map[string]string{
+		"x264": "H.264", "h264": "H.264", "h.264": "H.264", "avc": "H.264",
+		"x265": "H.265", "h265": "H.265", "h.265": "H.265", "hevc": "H.265",
+		"av1": "AV1", "xvid": "XviD", "divx": "DivX", "vp9": "VP9",
+	}
+	releaseSources = map[string]string{
+		"bluray": "BluRay", "blu-ray": "BluRay", "bdrip": "BluRay", "brrip": "BluRay", "bd": "BluRay",
+		"web-dl": "WEB-DL", "webdl": "WEB-DL", "webrip": "WEBRip", "web": "WEB",
+		"hdtv": "HDTV", "dvdrip": "DVD", "dvd": "DVD", "tv": "TV",
+	}
+)
+
+// releaseTokens splits a name into its words, keeping dots and dashes inside known tags (e.g. "h.264", "web-dl").
+func releaseTokens(s string) []string {
+	tokens := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '[' || r == ']' || r == '(' || r == ')' || r == ','
+	})
+
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		lower := strings.ToLower(token)
+		if releaseCodecs[lower] != "" || releaseSources[lower] != "" {
+			words = append(words, token)
+			continue
+		}
+		words = append(words, strings.FieldsFunc(token, func(r rune) bool { return r == '.' })...)
+	}
+	return words
+}
+
+func parseReleaseTags(r *Release, name string) {
+	for _, token := range releaseTokens(name) {
+		lower := strings.ToLower(token)
+		if codec, ok := releaseCodecs[lower]; ok && r.Codec == "" {
+			r.Codec = codec
+		}
+
+		// scene names put the group after the last dash (e.g. "x264-GROUP"), so tags are also looked up before it
+		if idx := strings.LastIndex(lower, "-"); idx > 0 && releaseSources[lower] == "" {
+			lower = lower[:idx]
+			if codec, ok := releaseCodecs[lower]; ok && r.Codec == "" {
+				r.Codec = codec
+			}
+		}
+
+		if source, ok := releaseSources[lower]; ok && r.Source == "" {
+			r.Source = source
+		}
+	}
+
+	r.Resolution, _ = parseResolution(name)
+}
+
+// parseResolution returns the first resolution found in s, in the form "1080p".
+func parseResolution(s string) (string, bool) {
+	m := releaseResolutionRegex.FindStringSubmatch(s)
+	switch {
+	case m == nil:
+		return "", false
+	case m[1] != "":
+		return m[1] + "p", true
+	case m[2] != "":
+		return m[2] + "p", true
+	default:
+		return "2160p", true
+	}
+}
+
+func parseReleaseEpisode(r *Release, name string) (start int) {
+	if m := releaseSeasonEpRegex.FindStringSubmatchIndex(name); m != nil {
+		r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+		r.Episode, _ = strconv.Atoi(name[m[4]:m[5]])
+		return m[0]
+	}
+
+	if m := releaseCrossEpRegex.FindStringSubmatchIndex(name); m != nil {
+		r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+		r.Episode, _ = strconv.Atoi(name[m[4]:m[5]])
+		return m[0]
+	}
+
+	if m := releaseAbsoluteEpRegex.FindStringSubmatchIndex(name); m != nil {
+		r.Episode, _ = strconv.Atoi(name[m[2]:m[3]])
+		return m[0]
+	}
+
+	if m := releaseEpisodeRegex.FindStringSubmatchIndex(name); m != nil {
+		r.Episode, _ = strconv.Atoi(name[m[2]:m[3]])
+		return m[0]
+	}
+	return -1
+}
+
+// ParseRelease extracts the release information encoded in a file name.
+func ParseRelease(name string) Release {
+	r := Release{}
+	if crc, ok := xdcc.CRC32FromFileName(name); ok {
+		r.CRC = crc
+	}
+
+	base := strings.TrimSpace(releaseExtRegex.ReplaceAllString(name, ""))
+	if m := releaseLeadingGroup.FindStringSubmatch(base); m != nil {
+		r.Group = strings.TrimSpace(m[1])
+		base = base[len(m[0]):]
+	}
+
+	base = strings.ReplaceAll(base, "_", " ")
+	parseReleaseTags(&r, base)
+
+	// the title is whatever precedes the first tag, with brackets removed
+	titlePart := strings.TrimSpace(releaseBracketsRegex.ReplaceAllString(base, " "))
+	if r.Group == "" && (r.Resolution != "" || r.Codec != "" || r.Source != "") {
+		if m := releaseTrailingGroup.FindStringSubmatch(titlePart); m != nil && strings.ContainsAny(titlePart, ". ") {
+			r.Group = m[1]
+			titlePart = titlePart[:len(titlePart)-len(m[0])]
+		}
+	}
+
+	end := len(titlePart)
+	if start := parseReleaseEpisode(&r, titlePart); start >= 0 {
+		end = start
+	}
+
+	if m := releaseYearRegex.FindStringIndex(titlePart); m != nil && m[0] > 0 && m[0] < end {
+		end = m[0]
+	}
+
+	words := releaseTokens(titlePart[:end])
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if releaseCodecs[lower] != "" || releaseSources[lower] != "" || releaseResolutionRegex.MatchString(word) {
+			words = words[:i]
+			break
+		}
+	}
+	r.Title = strings.TrimRight(strings.TrimSpace(strings.Join(words, " ")), " -")
+	return r
+}
+
+// EpisodeLabel formats the season and episode of a release (e.g. "S01E05", or "E05" without a season).
+func (r Release) EpisodeLabel() string {
+	if r.Episode == 0 {
+		return ""
+	}
+
+	if r.Season == 0 {
+		return fmt.Sprintf("E%02d", r.Episode)
+	}
+	return fmt.Sprintf("S%02dE%02d", r.Season, r.Episode)
+}
+
+// ReleaseFilter selects results by their release information. Empty or zero fields match any result.
+type ReleaseFilter struct {
+	Resolution string
+	Season     int
+	Episode    int
+}
+
+func (f *ReleaseFilter) Match(r Release) bool {
+	if f.Resolution != "" && !strings.EqualFold(f.resolution(), r.Resolution) {
+		return false
+	}
+
+	if f.Season > 0 && r.Season != f.Season {
+		return false
+	}
+
+	return f.Episode <= 0 || r.Episode == f.Episode
+}
+
+// resolution normalizes the resolution of the filter the way ParseRelease does,
+// so that e.g. "1080", "1080p" and "1920x1080" are the same and "4k" is "2160p".
+func (f *ReleaseFilter) resolution() string {
+	if resolution, ok := parseResolution(f.Resolution); ok {
+		return resolution
+	}
+	return strings.TrimSuffix(strings.ToLower(f.Resolution), "p") + "p"
+}
+
+// FilterResults returns the results whose release matches the filter.
+func FilterResults(results []XdccFileInfo, f ReleaseFilter) []XdccFileInfo {
+	filtered := make([]XdccFileInfo, 0, len(results))
+	for _, res := range results {
+		if f.Match(res.Release) {
+			filtered = append(filtered, res)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	tokenMatchWeight = 0.5
+	tokenOrderWeight = 0.1
+	proximityWeight  = 0.1
+	mirrorsWeight    = 0.15
+	getsWeight       = 0.15
+
+	// partialMatchScore is the score of a keyword matching only the beginning of a token.
+	partialMatchScore = 0.5
+)
+
+// keywordPositions returns, for each keyword, the score of its best match among tokens
+// and the position of the matching token, or -1 when no token matches.
+func keywordPositions(keywords []string, tokens []string) ([]float64, []int) {
+	scores := make([]float64, len(keywords))
+	positions := make([]int, len(keywords))
+	for i, keyword := range keywords {
+		positions[i] = -1
+		for j, token := range tokens {
+			if token == keyword {
+				scores[i], positions[i] = 1, j
+				break
+			}
+
+			if scores[i] == 0 && strings.HasPrefix(token, keyword) {
+				scores[i], positions[i] = partialMatchScore, j
+			}
+		}
+	}
+	return scores, positions
+}
+
+// textRelevance scores how well name matches keywords: by the fraction of keywords found
+// as tokens of name, by how many consecutive keywords appear in the same order and
+// by how close to each other the matching tokens are.
+func textRelevance(keywords []string, name string) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+
+	scores, positions := keywordPositions(keywords, tokenize(name))
+
+	matchScore := 0.0
+	matched := 0
+	minPos, maxPos := -1, -1
+	for i, score := range scores {
+		matchScore += score
+		if positions[i] < 0 {
+			continue
+		}
+
+		matched++
+		if minPos < 0 || positions[i] < minPos {
+			minPos = positions[i]
+		}
+		if positions[i] > maxPos {
+			maxPos = positions[i]
+		}
+	}
+	matchScore /= float64(len(keywords))
+
+	if matched == 0 {
+		return 0
+	}
+
+	orderScore := 1.0
+	if len(keywords) > 1 {
+		ordered := 0
+		for i := 1; i < len(positions); i++ {
+			if positions[i-1] >= 0 && positions[i] > positions[i-1] {
+				ordered++
+			}
+		}
+		orderScore = float64(ordered) / float64(len(keywords)-1)
+	}
+
+	proximityScore := float64(matched) / float64(maxPos-minPos+1)
+
+	return (tokenMatchWeight*matchScore + tokenOrderWeight*orderScore + proximityWeight*proximityScore) /
+		(tokenMatchWeight + tokenOrderWeight + proximityWeight)
+}
+
+// logScale maps n to [0, 1], relative to max, on a logarithmic scale.
+func logScale(n int, max int) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return math.Log1p(float64(n)) / math.Log1p(float64(max))
+}
+
+// ScoreRelevance sets the relevance of each result for keywords, combining the quality of the match
+// of its name with the number of mirrors offering the same file and the number of its downloads.
+func ScoreRelevance(keywords []string, results []XdccFileInfo) {
+	keywordTokens := tokenize(strings.Join(keywords, " "))
+
+	mirrors := make(map[groupKey]int)
+	maxMirrors, maxGets := 0, 0
+	for _, res := range results {
+		key := groupKey{name: NormalizeName(res.Name), size: res.Size}
+		mirrors[key]++
+		if mirrors[key] > maxMirrors {
+			maxMirrors = mirrors[key]
+		}
+
+		if res.Gets > maxGets {
+			maxGets = res.Gets
+		}
+	}
+
+	textWeight := tokenMatchWeight + tokenOrderWeight + proximityWeight
+	for i := range results {
+		res := &results[i]
+		key := groupKey{name: NormalizeName(res.Name), size: res.Size}
+
+		// a file offered by a single bot gets no mirror score
+		res.Relevance = textWeight*textRelevance(keywordTokens, res.Name) +
+			mirrorsWeight*logScale(mirrors[key]-1, maxMirrors-1) +
+			getsWeight*logScale(res.Gets, maxGets)
+	}
+}
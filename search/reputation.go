@@ -0,0 +1,39 @@
+package search
+
+import "xdcc-cli/xdcc"
+
+const (
+	// reputationWeight is the share of the relevance of a result given by the reputation of its bot.
+	reputationWeight = 0.2
+
+	// unknownReputation is the reputation of bots without transfer history.
+	unknownReputation = 0.5
+)
+
+// BotReputations scores bots in the range [0, 1] by their transfer history, keyed by xdcc.BotKey.
+type BotReputations map[string]float64
+
+func (r BotReputations) of(file xdcc.IRCFile) float64 {
+	if reputation, ok := r[xdcc.BotKey(file.Network, file.UserName)]; ok {
+		return reputation
+	}
+	return unknownReputation
+}
+
+// SetBotReputations makes the relevance of results depend on the reputation of their bots,
+// so that reliable and fast bots are preferred.
+func (registry *ProviderAggregator) SetBotReputations(r BotReputations) {
+	registry.reputations = r
+}
+
+// ApplyBotReputations blends the relevance of each result with the reputation of its bot.
+func ApplyBotReputations(results []XdccFileInfo, r BotReputations) {
+	if len(r) == 0 {
+		return
+	}
+
+	for i := range results {
+		res := &results[i]
+		res.Relevance = (1-reputationWeight)*res.Relevance + reputationWeight*r.of(res.URL)
+	}
+}
@@ -4,14 +4,29 @@ import (
 	"errors"
 	"strconv"
 	"sync"
+	"time"
 	"xdcc-cli/xdcc"
 )
 
 type XdccFileInfo struct {
-	URL  xdcc.IRCFile
-	Name string
-	Size int64
-	Slot int
+	URL      xdcc.IRCFile
+	Name     string
+	Size     int64
+	Slot     int
+	Provider string
+	Release  Release
+
+	// Gets is the number of downloads reported by the provider, if any.
+	Gets int `json:",omitempty"`
+
+	// BotSpeed is the record transfer rate of the bot, in bytes per second, if reported by the provider.
+	BotSpeed int64 `json:",omitempty"`
+
+	// LastSeen is the last time the provider found the pack on the bot, nil if not reported.
+	LastSeen *time.Time `json:",omitempty"`
+
+	// Relevance is the score of the result for the keywords of the search, in the range [0, 1].
+	Relevance float64 `json:",omitempty"`
 }
 
 type XdccSearchProvider interface {
@@ -20,54 +35,128 @@ type XdccSearchProvider interface {
 
 type ProviderAggregator struct {
 	providerList []XdccSearchProvider
+	weights      []float64
+	limit        int
+	reputations  BotReputations
 }
 
 const MaxProviders = 100
 
+const defaultProviderWeight = 1
+
 func NewProviderAggregator(providers ...XdccSearchProvider) *ProviderAggregator {
-	return &ProviderAggregator{
-		providerList: providers,
+	registry := &ProviderAggregator{}
+	for _, p := range providers {
+		registry.AddProvider(p)
 	}
+	return registry
 }
 
 func (registry *ProviderAggregator) AddProvider(provider XdccSearchProvider) {
+	registry.AddWeightedProvider(provider, defaultProviderWeight)
+}
+
+// AddWeightedProvider adds a provider whose results take precedence over the ones of
+// lighter providers, when the same file is found by both.
+func (registry *ProviderAggregator) AddWeightedProvider(provider XdccSearchProvider, weight float64) {
 	registry.providerList = append(registry.providerList, provider)
+	registry.weights = append(registry.weights, weight)
 }
 
 const MaxResults = 1024
 
-func (registry *ProviderAggregator) Search(keywords []string) ([]XdccFileInfo, error) {
-	allResults := make(map[xdcc.IRCFile]XdccFileInfo)
+// SetLimit sets the maximum number of results retrieved from each provider, following
+// the result pages of the providers supporting them. A limit not positive means no limit.
+func (registry *ProviderAggregator) SetLimit(limit int) {
+	registry.limit = limit
+}
 
+// searchProviders runs the search on all the providers at once, calling onResults
+// with the results of each provider, or of each page of results, as soon as they are found.
+func (registry *ProviderAggregator) searchProviders(keywords []string, onResults func(weight float64, results []XdccFileInfo)) {
 	mtx := sync.Mutex{}
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(registry.providerList))
-	for _, p := range registry.providerList {
-		go func(p XdccSearchProvider) {
+	for i, p := range registry.providerList {
+		go func(p XdccSearchProvider, weight float64) {
+			defer wg.Done()
+
+			push := func(results []XdccFileInfo) {
+				for i := range results {
+					results[i].Release = ParseRelease(results[i].Name)
+				}
+
+				mtx.Lock()
+				onResults(weight, results)
+				mtx.Unlock()
+			}
+
+			if paged, ok := p.(PagedSearchProvider); ok {
+				paged.SearchPages(keywords, registry.limit, push)
+				return
+			}
+
 			resList, err := p.Search(keywords)
 			if err != nil {
 				return
 			}
 
-			mtx.Lock()
-			for _, res := range resList {
-				allResults[res.URL] = res
+			if registry.limit > 0 && len(resList) > registry.limit {
+				resList = resList[:registry.limit]
 			}
-			mtx.Unlock()
-
-			wg.Done()
-		}(p)
+			push(resList)
+		}(p, registry.weights[i])
 	}
 	wg.Wait()
+}
+
+func (registry *ProviderAggregator) Search(keywords []string) ([]XdccFileInfo, error) {
+	allResults := make(map[xdcc.IRCFile]XdccFileInfo)
+	resultWeights := make(map[xdcc.IRCFile]float64)
+
+	registry.searchProviders(keywords, func(weight float64, resList []XdccFileInfo) {
+		for _, res := range resList {
+			if w, found := resultWeights[res.URL]; !found || weight > w {
+				allResults[res.URL] = res
+				resultWeights[res.URL] = weight
+			}
+		}
+	})
 
 	results := make([]XdccFileInfo, 0, MaxResults)
 	for _, res := range allResults {
 		results = append(results, res)
 	}
+
+	ScoreRelevance(keywords, results)
+	ApplyBotReputations(results, registry.reputations)
+	SortResults(results, []SortKey{{Field: "relevance"}, {Field: "name"}, {Field: "network"}, {Field: "bot"}, {Field: "slot"}})
 	return results, nil
 }
 
+// SearchStream runs the search in the background, sending results through the returned channel
+// as soon as they are found. The channel is closed once all the providers are done.
+// Since results are sent right away, a file found by several providers is reported by the first
+// one finding it, regardless of the weights.
+func (registry *ProviderAggregator) SearchStream(keywords []string) <-chan XdccFileInfo {
+	results := make(chan XdccFileInfo, MaxResults)
+	go func() {
+		defer close(results)
+
+		found := make(map[xdcc.IRCFile]bool)
+		registry.searchProviders(keywords, func(_ float64, resList []XdccFileInfo) {
+			for _, res := range resList {
+				if !found[res.URL] {
+					found[res.URL] = true
+					results <- res
+				}
+			}
+		})
+	}()
+	return results
+}
+
 const (
 	KiloByte = 1024
 	MegaByte = KiloByte * 1024
@@ -96,3 +185,20 @@ func parseFileSize(sizeStr string) (int64, error) {
 	}
 	return -1, errors.New("unable to parse: " + sizeStr)
 }
+
+// FindMirrors returns the sources, among results, of files having the same name and size of file.
+// Names are compared exactly, since transfers resume and merge files by name;
+// files of unknown size have no mirrors.
+func FindMirrors(results []XdccFileInfo, file XdccFileInfo) []xdcc.IRCFile {
+	mirrors := make([]xdcc.IRCFile, 0)
+	if file.Size < 0 {
+		return mirrors
+	}
+
+	for _, res := range results {
+		if res.URL != file.URL && res.Size == file.Size && res.Name == file.Name {
+			mirrors = append(mirrors, res.URL)
+		}
+	}
+	return mirrors
+}
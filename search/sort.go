@@ -0,0 +1,115 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+type compareFunc func(a, b *XdccFileInfo) int
+
+func compareStrings(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+var sortFields = map[string]compareFunc{
+	"name": func(a, b *XdccFileInfo) int {
+		return compareStrings(a.Name, b.Name)
+	},
+	"size": func(a, b *XdccFileInfo) int {
+		return compareInts(a.Size, b.Size)
+	},
+	"network": func(a, b *XdccFileInfo) int {
+		return compareStrings(a.URL.Network, b.URL.Network)
+	},
+	"bot": func(a, b *XdccFileInfo) int {
+		return compareStrings(a.URL.UserName, b.URL.UserName)
+	},
+	"slot": func(a, b *XdccFileInfo) int {
+		return compareInts(int64(a.Slot), int64(b.Slot))
+	},
+	"provider": func(a, b *XdccFileInfo) int {
+		return compareStrings(a.Provider, b.Provider)
+	},
+	"gets": func(a, b *XdccFileInfo) int {
+		return compareInts(int64(a.Gets), int64(b.Gets))
+	},
+	// the most relevant results come first in ascending order
+	"relevance": func(a, b *XdccFileInfo) int {
+		return compareFloats(b.Relevance, a.Relevance)
+	},
+}
+
+var ErrInvalidSortKey = errors.New("invalid sort key")
+
+// ParseSortKeys parses a comma separated list of sort keys of the form field[:asc|:desc],
+// e.g. "size:desc,name".
+func ParseSortKeys(s string) ([]SortKey, error) {
+	keys := make([]SortKey, 0)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		key := SortKey{Field: field}
+		if idx := strings.Index(field, ":"); idx >= 0 {
+			key.Field = field[:idx]
+			switch strings.ToLower(field[idx+1:]) {
+			case "asc":
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, fmt.Errorf("%w: %s", ErrInvalidSortKey, field)
+			}
+		}
+
+		key.Field = strings.ToLower(key.Field)
+		if _, ok := sortFields[key.Field]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSortKey, field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SortResults sorts results in place according to the given keys,
+// using each key to break ties left by the previous ones.
+func SortResults(results []XdccFileInfo, keys []SortKey) {
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, key := range keys {
+			cmp := sortFields[key.Field](&results[i], &results[j])
+			if key.Desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
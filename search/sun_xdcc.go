@@ -6,14 +6,21 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	sunXdccURL             = "http://sunxdcc.com/deliver.php"
 	sunXdccNumberOfEntries = 8
+	sunXdccProviderName    = "sunxdcc"
+	sunXdccDefaultMaxPages = 1
 )
 
-type SunXdccProvider struct{}
+type SunXdccProvider struct {
+	BaseURL  string
+	Timeout  time.Duration
+	MaxPages int
+}
 
 func (p *SunXdccProvider) parseResponseEntry(entry *SunXdccResponse, index int) (*XdccFileInfo, error) {
 	info := &XdccFileInfo{}
@@ -34,7 +41,10 @@ func (p *SunXdccProvider) parseResponseEntry(entry *SunXdccResponse, index int)
 		return nil, err
 	}
 
+	info.Gets = parseGets(entry.Gets[index])
+	info.BotSpeed = parseSpeed(entry.Botrec[index])
 	info.Slot = slot
+	info.Provider = sunXdccProviderName
 	return info, nil
 }
 
@@ -49,29 +59,53 @@ type SunXdccResponse struct {
 	Fname   []string
 }
 
-func (p *SunXdccProvider) Search(keywords []string) ([]XdccFileInfo, error) {
-	keywordString := strings.Join(keywords, " ")
-	searchkey := strings.Join(strings.Fields(keywordString), "+")
+func (p *SunXdccProvider) fetchPage(client *http.Client, searchkey string, page int) ([]XdccFileInfo, bool, error) {
 	// see https://sunxdcc.com/#api for API definition
-	httpResp, err := http.Get(sunXdccURL + "?sterm=" + searchkey)
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = sunXdccURL
+	}
+
+	httpResp, err := client.Get(baseURL + "?sterm=" + searchkey + "&page=" + strconv.Itoa(page))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	defer httpResp.Body.Close()
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code error: %d %s", httpResp.StatusCode, httpResp.Status)
+		return nil, false, fmt.Errorf("status code error: %d %s", httpResp.StatusCode, httpResp.Status)
 	}
 
 	resp, err := p.parseResponse(httpResp)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if !p.validateResult(resp) {
-		return nil, fmt.Errorf("parse Error, not all fields have the same size")
+		return nil, false, fmt.Errorf("parse Error, not all fields have the same size")
 	}
-	return p.parseResults(resp)
+
+	fileInfos, err := p.parseResults(resp)
+	return fileInfos, len(resp.Botrec) > 0, err
+}
+
+func (p *SunXdccProvider) SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error {
+	keywordString := strings.Join(keywords, " ")
+	searchkey := strings.Join(strings.Fields(keywordString), "+")
+
+	maxPages := p.MaxPages
+	if maxPages <= 0 && limit <= 0 {
+		maxPages = sunXdccDefaultMaxPages
+	}
+
+	client := &http.Client{Timeout: p.Timeout}
+	return fetchPages(limit, maxPages, func(page int) ([]XdccFileInfo, bool, error) {
+		return p.fetchPage(client, searchkey, page)
+	}, onPage)
+}
+
+func (p *SunXdccProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	return collectPages(p, keywords, 0)
 }
 
 func (p *SunXdccProvider) parseResults(resp *SunXdccResponse) ([]XdccFileInfo, error) {
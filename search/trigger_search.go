@@ -0,0 +1,132 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xdcc-cli/xdcc"
+)
+
+const (
+	triggerSearchProviderName = "trigger"
+	DefaultSearchTrigger      = "@find"
+	defaultTriggerWindow      = 20 * time.Second
+)
+
+// TriggerChannel is a channel whose bots answer to a search trigger, such as "!search" or "@find".
+type TriggerChannel struct {
+	Network string
+	Channel string
+	Trigger string
+}
+
+// TriggerSearchProvider searches files by issuing a search trigger in a set of channels
+// and collecting the replies of all the bots for a bounded window of time.
+type TriggerSearchProvider struct {
+	Channels []TriggerChannel
+	SSLOnly  bool
+	Window   time.Duration
+}
+
+// matches replies of the form "/msg Bot xdcc send #5 [700M] file.mkv"
+var triggerReplyRegex = regexp.MustCompile(`(?i)/msg\s+(\S+)\s+xdcc\s+send\s+#?(\d+)\s*(?:\[\s*<?([^\]]*?)\s*\])?\s*(.*)$`)
+
+// ParseTriggerReply parses the replies sent by a bot, in a channel, after a search trigger.
+func ParseTriggerReply(channel TriggerChannel, nick string, lines []string) []XdccFileInfo {
+	bot := xdcc.IRCBot{Network: channel.Network, Channel: channel.Channel, Name: nick}
+
+	fileInfos := ParseBotSearchReply(bot, lines)
+	for i := range fileInfos {
+		fileInfos[i].Provider = triggerSearchProviderName
+	}
+
+	for _, line := range lines {
+		matches := triggerReplyRegex.FindStringSubmatch(line)
+		if matches == nil || strings.TrimSpace(matches[4]) == "" {
+			continue
+		}
+
+		slot, err := strconv.Atoi(matches[2])
+		if err != nil {
+			continue
+		}
+
+		bot.Name = matches[1]
+		info := XdccFileInfo{
+			URL:      bot.GetFile(slot),
+			Name:     strings.TrimSpace(matches[4]),
+			Size:     -1,
+			Slot:     slot,
+			Provider: triggerSearchProviderName,
+		}
+
+		if size, err := parsePacklistSize(matches[3]); err == nil {
+			info.Size = size
+		}
+		fileInfos = append(fileInfos, info)
+	}
+	return fileInfos
+}
+
+func (p *TriggerSearchProvider) searchChannel(channel TriggerChannel, keywords []string) ([]XdccFileInfo, error) {
+	trigger := channel.Trigger
+	if trigger == "" {
+		trigger = DefaultSearchTrigger
+	}
+
+	window := p.Window
+	if window <= 0 {
+		window = defaultTriggerWindow
+	}
+
+	replies, err := xdcc.Query(xdcc.QueryConfig{
+		Network:     channel.Network,
+		SSLOnly:     p.SSLOnly,
+		Channels:    []string{channel.Channel},
+		Targets:     []string{channel.Channel},
+		AnySender:   true,
+		Timeout:     window,
+		IdleTimeout: window,
+	}, &xdcc.TriggerReq{Trigger: trigger, Terms: strings.Join(keywords, " ")})
+	if err != nil {
+		return nil, err
+	}
+
+	linesByNick := make(map[string][]string)
+	for _, r := range replies {
+		linesByNick[r.Nick] = append(linesByNick[r.Nick], r.Text)
+	}
+
+	fileInfos := make([]XdccFileInfo, 0)
+	for nick, lines := range linesByNick {
+		fileInfos = append(fileInfos, ParseTriggerReply(channel, nick, lines)...)
+	}
+	return fileInfos, nil
+}
+
+func (p *TriggerSearchProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	mtx := sync.Mutex{}
+	fileInfos := make([]XdccFileInfo, 0)
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(p.Channels))
+	for _, channel := range p.Channels {
+		go func(channel TriggerChannel) {
+			defer wg.Done()
+
+			res, err := p.searchChannel(channel, keywords)
+			if err != nil {
+				return
+			}
+
+			mtx.Lock()
+			fileInfos = append(fileInfos, res...)
+			mtx.Unlock()
+		}(channel)
+	}
+	wg.Wait()
+
+	return fileInfos, nil
+}
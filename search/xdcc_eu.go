@@ -6,16 +6,23 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 	"xdcc-cli/xdcc"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-type XdccEuProvider struct{}
+type XdccEuProvider struct {
+	BaseURL  string
+	Timeout  time.Duration
+	MaxPages int
+}
 
 const (
 	xdccEuURL             = "https://www.xdcc.eu/search.php"
 	xdccEuNumberOfEntries = 7
+	xdccEuProviderName    = "xdcc.eu"
+	xdccEuDefaultMaxPages = 1
 )
 
 func (p *XdccEuProvider) parseFields(fields []string) (*XdccFileInfo, error) {
@@ -32,6 +39,7 @@ func (p *XdccEuProvider) parseFields(fields []string) (*XdccFileInfo, error) {
 		return nil, err
 	}
 
+	fInfo.Gets = parseGets(fields[4])
 	fInfo.Size, _ = parseFileSize(fields[5]) // ignoring error
 
 	fInfo.Name = fields[6]
@@ -40,26 +48,30 @@ func (p *XdccEuProvider) parseFields(fields []string) (*XdccFileInfo, error) {
 	}
 
 	fInfo.Slot = slot
+	fInfo.Provider = xdccEuProviderName
 	return fInfo, nil
 }
 
-func (p *XdccEuProvider) Search(keywords []string) ([]XdccFileInfo, error) {
-	keywordString := strings.Join(keywords, " ")
-	searchkey := strings.Join(strings.Fields(keywordString), "+")
-	res, err := http.Get(xdccEuURL + "?searchkey=" + searchkey)
+func (p *XdccEuProvider) fetchPage(client *http.Client, searchkey string, page int) ([]XdccFileInfo, bool, error) {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = xdccEuURL
+	}
+
+	res, err := client.Get(baseURL + "?searchkey=" + searchkey + "&p=" + strconv.Itoa(page+1))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+		return nil, false, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
 	}
 
 	// Load the HTML document
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	fileInfos := make([]XdccFileInfo, 0)
@@ -86,5 +98,26 @@ func (p *XdccEuProvider) Search(keywords []string) ([]XdccFileInfo, error) {
 			}
 		}
 	})
-	return fileInfos, nil
+
+	// pages are over when a page has no results
+	return fileInfos, len(fileInfos) > 0, nil
+}
+
+func (p *XdccEuProvider) SearchPages(keywords []string, limit int, onPage func([]XdccFileInfo)) error {
+	keywordString := strings.Join(keywords, " ")
+	searchkey := strings.Join(strings.Fields(keywordString), "+")
+
+	maxPages := p.MaxPages
+	if maxPages <= 0 && limit <= 0 {
+		maxPages = xdccEuDefaultMaxPages
+	}
+
+	client := &http.Client{Timeout: p.Timeout}
+	return fetchPages(limit, maxPages, func(page int) ([]XdccFileInfo, bool, error) {
+		return p.fetchPage(client, searchkey, page)
+	}, onPage)
+}
+
+func (p *XdccEuProvider) Search(keywords []string) ([]XdccFileInfo, error) {
+	return collectPages(p, keywords, 0)
 }
@@ -141,3 +141,31 @@ func (printer *TablePrinter) Print() {
 		fmt.Println(printer.renderLine(colWidths))
 	}
 }
+
+// streamColWidths returns the widths used by the streaming methods, which cannot depend
+// on rows not printed yet: the maximum width of each column, or the width of its header.
+func (printer *TablePrinter) streamColWidths() []int {
+	widths := make([]int, printer.NumCols())
+	for i := range widths {
+		widths[i] = len(printer.Headers[i]) + paddingDefault
+		if printer.MaxWidths != nil && printer.MaxWidths[i] > 0 {
+			widths[i] = printer.MaxWidths[i]
+		}
+	}
+	return widths
+}
+
+// PrintHeader prints the header of a table whose rows are printed as they are produced, through PrintRow.
+func (printer *TablePrinter) PrintHeader() {
+	printer.renderHeader(printer.streamColWidths())
+}
+
+// PrintRow prints a single row, cut to the maximum column widths.
+func (printer *TablePrinter) PrintRow(r Row) {
+	fmt.Println(printer.renderRow(r, printer.streamColWidths()))
+}
+
+// PrintFooter closes a table printed through PrintHeader and PrintRow.
+func (printer *TablePrinter) PrintFooter() {
+	fmt.Println(printer.renderLine(printer.streamColWidths()))
+}
@@ -1,8 +1,56 @@
 package util
 
+import "strings"
+
 func CutStr(s string, maxSize int) string {
 	if len(s) <= maxSize {
 		return s
 	}
 	return s[:maxSize-3] + "..."
 }
+
+// StripIRCFormatting removes bold, color, italic, underline and reset control codes from s.
+func StripIRCFormatting(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\x02', '\x0f', '\x16', '\x1d', '\x1f':
+		case '\x03':
+			// skip the optional foreground and background color numbers
+			i += skipColorCode(s[i+1:])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func skipColorCode(s string) int {
+	n := skipDigits(s, 2)
+	if n > 0 && n < len(s)-1 && s[n] == ',' && isDigit(s[n+1]) {
+		n += 1 + skipDigits(s[n+1:], 2)
+	}
+	return n
+}
+
+func skipDigits(s string, max int) int {
+	n := 0
+	for n < len(s) && n < max && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ContainsFold tells whether list contains s, ignoring case.
+func ContainsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyMatch reports whether all the characters of pattern appear in s in the same order,
+// ignoring case and whitespace in the pattern.
+func FuzzyMatch(pattern string, s string) bool {
+	s = strings.ToLower(s)
+	pos := 0
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		idx := strings.IndexRune(s[pos:], r)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(string(r))
+	}
+	return true
+}
+
+// EditDistance returns the Levenshtein distance between a and b.
+func EditDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
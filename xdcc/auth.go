@@ -0,0 +1,316 @@
+package xdcc
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"xdcc-cli/util"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+// authentication methods of NetworkConfig.Auth
+const (
+	AuthAuto     = ""         // SASL PLAIN when a password is set, falling back to NickServ
+	AuthSASL     = "sasl"     // SASL PLAIN only
+	AuthExternal = "external" // SASL EXTERNAL, with the client certificate
+	AuthNickServ = "nickserv" // NickServ IDENTIFY only
+	AuthNone     = "none"
+)
+
+const (
+	saslChunkSize = 400
+	authTimeout   = 30 * time.Second
+
+	rplLoggedIn     = "900"
+	errNickLocked   = "902"
+	rplSaslSuccess  = "903"
+	errSaslFail     = "904"
+	errSaslTooLong  = "905"
+	errSaslAborted  = "906"
+	errSaslAlready  = "907"
+	rplSaslMechs    = "908"
+	nickServ        = "NickServ"
+	capCommand      = "CAP"
+	capSasl         = "sasl"
+	capSubcmdReq    = "REQ"
+	capSubcmdAck    = "ACK"
+	capSubcmdNak    = "NAK"
+	capSubcmdEnd    = "END"
+	authenticateCmd = "AUTHENTICATE"
+)
+
+var ErrAuthenticationFailed = errors.New("authentication failed")
+
+// notices sent by NickServ on identification
+var (
+	nickServSuccessNotices = []string{
+		"you are now identified",
+		"you are now logged in",
+		"you are now recognized",
+		"password accepted",
+	}
+	nickServFailureNotices = []string{
+		"invalid password",
+		"incorrect password",
+		"password incorrect",
+		"authentication failed",
+		"is not registered",
+		"isn't registered",
+	}
+)
+
+func containsAny(text string, notices []string) bool {
+	text = strings.ToLower(text)
+	for _, notice := range notices {
+		if strings.Contains(text, notice) {
+			return true
+		}
+	}
+	return false
+}
+
+// password returns the password of the account, read from the environment variable
+// named by PasswordEnv when not set in the configuration.
+func (conf *NetworkConfig) password() string {
+	if conf.Password == "" && conf.PasswordEnv != "" {
+		return os.Getenv(conf.PasswordEnv)
+	}
+	return conf.Password
+}
+
+// account returns the name of the account to identify with.
+func (conf *NetworkConfig) account(nick string) string {
+	if conf.Account != "" {
+		return conf.Account
+	}
+	return nick
+}
+
+func (conf *NetworkConfig) useSASL() bool {
+	switch strings.ToLower(conf.Auth) {
+	case AuthExternal:
+		return true
+	case AuthAuto, AuthSASL:
+		return conf.password() != ""
+	}
+	return false
+}
+
+func (conf *NetworkConfig) useNickServ() bool {
+	switch strings.ToLower(conf.Auth) {
+	case AuthAuto, AuthNickServ:
+		return conf.password() != ""
+	}
+	return false
+}
+
+// authenticator identifies the client to the network before channels are joined:
+// through SASL during registration when possible, through NickServ otherwise.
+type authenticator struct {
+	conf     NetworkConfig
+	onReady  func(conn *irc.Conn)
+	onFailed func(err error)
+
+	mtx           sync.Mutex
+	authenticated bool
+	saslPending   bool
+	registered    bool
+	ready         bool
+	timer         *time.Timer
+}
+
+// setupAuthentication registers the handlers authenticating the client on the network.
+// onReady is called on each connection once the client is identified, or right after
+// registration when no authentication is configured; onFailed is called when the
+// credentials are refused.
+func setupAuthentication(conn *irc.Conn, network string, onReady func(conn *irc.Conn), onFailed func(err error)) {
+	auth := &authenticator{conf: GetNetworkConfig(network), onReady: onReady, onFailed: onFailed}
+
+	// CAP REQ is sent on connection, before NICK and USER (see newIRCConfig)
+	conn.HandleFunc(irc.REGISTER, func(conn *irc.Conn, line *irc.Line) {
+		auth.mtx.Lock()
+		defer auth.mtx.Unlock()
+
+		auth.authenticated = false
+		auth.registered = false
+		auth.ready = false
+		auth.saslPending = auth.conf.useSASL()
+	})
+
+	conn.HandleFunc(irc.CAP, auth.handleCap)
+	conn.HandleFunc(authenticateCmd, auth.handleAuthenticate)
+	for _, numeric := range []string{rplSaslSuccess, errSaslAlready} {
+		conn.HandleFunc(numeric, func(conn *irc.Conn, line *irc.Line) {
+			auth.endSASL(conn, true)
+		})
+	}
+	for _, numeric := range []string{errNickLocked, errSaslFail, errSaslTooLong, errSaslAborted, rplSaslMechs} {
+		conn.HandleFunc(numeric, func(conn *irc.Conn, line *irc.Line) {
+			auth.endSASL(conn, false)
+		})
+	}
+
+	// sent on SASL success as well, before the registration is complete
+	conn.HandleFunc(rplLoggedIn, func(conn *irc.Conn, line *irc.Line) {
+		auth.setAuthenticated(conn)
+	})
+
+	conn.HandleFunc(irc.CONNECTED, auth.handleConnected)
+	conn.HandleFunc(irc.NOTICE, auth.handleNickServ)
+	conn.HandleFunc(irc.DISCONNECTED, func(conn *irc.Conn, line *irc.Line) {
+		auth.mtx.Lock()
+		defer auth.mtx.Unlock()
+		if auth.timer != nil {
+			auth.timer.Stop()
+		}
+	})
+}
+
+func (auth *authenticator) handleCap(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) < 3 || !util.ContainsFold(strings.Fields(line.Args[2]), capSasl) {
+		return
+	}
+
+	switch strings.ToUpper(line.Args[1]) {
+	case capSubcmdAck:
+		mechanism := "PLAIN"
+		if strings.EqualFold(auth.conf.Auth, AuthExternal) {
+			mechanism = "EXTERNAL"
+		}
+		conn.Raw(authenticateCmd + " " + mechanism)
+	case capSubcmdNak:
+		auth.endSASL(conn, false)
+	}
+}
+
+func (auth *authenticator) handleAuthenticate(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) < 1 || line.Args[0] != "+" {
+		return
+	}
+
+	if strings.EqualFold(auth.conf.Auth, AuthExternal) {
+		conn.Raw(authenticateCmd + " +")
+		return
+	}
+
+	account := auth.conf.account(conn.Me().Nick)
+	payload := base64.StdEncoding.EncodeToString([]byte(account + "\x00" + account + "\x00" + auth.conf.password()))
+	for len(payload) >= saslChunkSize {
+		conn.Raw(authenticateCmd + " " + payload[:saslChunkSize])
+		payload = payload[saslChunkSize:]
+	}
+
+	// an empty chunk ends a payload whose length is a multiple of the chunk size
+	if payload == "" {
+		payload = "+"
+	}
+	conn.Raw(authenticateCmd + " " + payload)
+}
+
+// endSASL ends the capability negotiation, letting the server complete the registration.
+func (auth *authenticator) endSASL(conn *irc.Conn, success bool) {
+	auth.mtx.Lock()
+	defer auth.mtx.Unlock()
+
+	if !auth.saslPending {
+		return
+	}
+	auth.saslPending = false
+	auth.authenticated = success
+	conn.Cap(capSubcmdEnd)
+}
+
+func (auth *authenticator) handleConnected(conn *irc.Conn, line *irc.Line) {
+	auth.mtx.Lock()
+	auth.registered = true
+	auth.saslPending = false
+	authenticated := auth.authenticated
+	auth.mtx.Unlock()
+
+	if authenticated || strings.EqualFold(auth.conf.Auth, AuthNone) {
+		auth.setReady(conn)
+		return
+	}
+
+	if !auth.conf.useNickServ() {
+		if auth.conf.useSASL() {
+			// SASL was required, but refused or not supported
+			auth.onFailed(ErrAuthenticationFailed)
+		} else {
+			auth.setReady(conn)
+		}
+		return
+	}
+
+	password := auth.conf.password()
+	if auth.conf.Account != "" {
+		conn.Privmsg(nickServ, "IDENTIFY "+auth.conf.Account+" "+password)
+	} else {
+		conn.Privmsg(nickServ, "IDENTIFY "+password)
+	}
+
+	// networks without services never answer, go on unidentified
+	auth.mtx.Lock()
+	auth.timer = time.AfterFunc(authTimeout, func() {
+		auth.setReady(conn)
+	})
+	auth.mtx.Unlock()
+}
+
+func (auth *authenticator) handleNickServ(conn *irc.Conn, line *irc.Line) {
+	if !strings.EqualFold(line.Nick, nickServ) {
+		return
+	}
+
+	auth.mtx.Lock()
+	identifying := auth.timer != nil
+	auth.mtx.Unlock()
+	if !identifying {
+		return
+	}
+
+	if containsAny(line.Text(), nickServSuccessNotices) {
+		auth.setAuthenticated(conn)
+	} else if containsAny(line.Text(), nickServFailureNotices) {
+		auth.mtx.Lock()
+		if auth.timer != nil {
+			auth.timer.Stop()
+			auth.timer = nil
+		}
+		auth.mtx.Unlock()
+		auth.onFailed(errors.New(ErrAuthenticationFailed.Error() + ": " + line.Text()))
+	}
+}
+
+// setAuthenticated records the identification, going on if the registration is complete.
+func (auth *authenticator) setAuthenticated(conn *irc.Conn) {
+	auth.mtx.Lock()
+	auth.authenticated = true
+	registered := auth.registered
+	auth.mtx.Unlock()
+
+	if registered {
+		auth.setReady(conn)
+	}
+}
+
+// setReady calls onReady once per connection.
+func (auth *authenticator) setReady(conn *irc.Conn) {
+	auth.mtx.Lock()
+	if auth.ready || !conn.Connected() {
+		auth.mtx.Unlock()
+		return
+	}
+	auth.ready = true
+	if auth.timer != nil {
+		auth.timer.Stop()
+		auth.timer = nil
+	}
+	auth.mtx.Unlock()
+
+	auth.onReady(conn)
+}
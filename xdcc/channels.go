@@ -0,0 +1,237 @@
+package xdcc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"xdcc-cli/util"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+// BotConfig holds the settings of a specific bot of a network.
+type BotConfig struct {
+	// Channels must be joined, besides the ones of the network, before requesting packs to the bot.
+	Channels []string `toml:"channels,omitempty"`
+}
+
+const (
+	errNoSuchChannel  = "403"
+	errTooManyChans   = "405"
+	errLinkChannel    = "470" // forwarded to another channel
+	errChannelIsFull  = "471"
+	errInviteOnlyChan = "473"
+	errBannedFromChan = "474"
+	errBadChannelKey  = "475"
+	errNeedReggedNick = "477"
+	inviteTimeout     = 30 * time.Second
+	joinTimeout       = 2 * time.Minute
+)
+
+var (
+	ErrInviteTimeout = errors.New("invite only channel, no invite received")
+	ErrJoinTimeout   = errors.New("timed out joining channels")
+)
+
+// channelsFor returns the channels to join before sending requests to bot, whose
+// channel is channel: the ones of the network, the ones of the bot, then channel.
+func (conf *NetworkConfig) channelsFor(bot string, channel string) []string {
+	channels := make([]string, 0, len(conf.Channels)+1)
+	add := func(list ...string) {
+		for _, c := range list {
+			if c != "" && !util.ContainsFold(channels, c) {
+				channels = append(channels, c)
+			}
+		}
+	}
+
+	add(conf.Channels...)
+	for name, botConf := range conf.Bots {
+		if strings.EqualFold(name, bot) {
+			add(botConf.Channels...)
+		}
+	}
+	add(channel)
+	return channels
+}
+
+// channelKey returns the key of channel, if any.
+func (conf *NetworkConfig) channelKey(channel string) string {
+	for name, key := range conf.ChannelKeys {
+		if strings.EqualFold(name, channel) {
+			return key
+		}
+	}
+	return ""
+}
+
+// channelJoiner joins a set of required channels, calling onJoined once all of them are joined,
+// or onFailed when one of them cannot be joined.
+type channelJoiner struct {
+	conf     NetworkConfig
+	channels []string
+	inviters []string
+	onJoined func(conn *irc.Conn)
+	onFailed func(err error)
+
+	mtx     sync.Mutex
+	joined  map[string]bool
+	invites map[string]*time.Timer
+	timer   *time.Timer
+	done    bool
+}
+
+// setupChannelJoins registers the handlers tracking the joins of channels. Invites to
+// the channels, or sent by inviters, are accepted; a channel being invite only is
+// not an error until inviteTimeout passes without invites.
+func setupChannelJoins(conn *irc.Conn, network string, channels []string, inviters []string, onJoined func(conn *irc.Conn), onFailed func(err error)) *channelJoiner {
+	joiner := &channelJoiner{
+		conf:     GetNetworkConfig(network),
+		channels: channels,
+		inviters: inviters,
+		onJoined: onJoined,
+		onFailed: onFailed,
+		joined:   make(map[string]bool),
+		invites:  make(map[string]*time.Timer),
+	}
+
+	conn.HandleFunc(irc.JOIN, joiner.handleJoin)
+	conn.HandleFunc(irc.INVITE, joiner.handleInvite)
+	for _, numeric := range []string{errNoSuchChannel, errTooManyChans, errLinkChannel, errChannelIsFull, errInviteOnlyChan, errBannedFromChan, errBadChannelKey, errNeedReggedNick} {
+		conn.HandleFunc(numeric, joiner.handleJoinError)
+	}
+	conn.HandleFunc(irc.DISCONNECTED, func(conn *irc.Conn, line *irc.Line) {
+		joiner.mtx.Lock()
+		defer joiner.mtx.Unlock()
+		joiner.stopTimers()
+	})
+	return joiner
+}
+
+// join joins all the channels, starting over on each connection.
+func (joiner *channelJoiner) join(conn *irc.Conn) {
+	joiner.mtx.Lock()
+	joiner.joined = make(map[string]bool)
+	joiner.done = false
+	joiner.stopTimers()
+	joiner.timer = time.AfterFunc(joinTimeout, func() {
+		joiner.fail(fmt.Errorf("%w: %s", ErrJoinTimeout, strings.Join(joiner.missingChannels(), ", ")))
+	})
+	joiner.mtx.Unlock()
+
+	for _, channel := range joiner.channels {
+		joiner.joinChannel(conn, channel)
+	}
+}
+
+func (joiner *channelJoiner) joinChannel(conn *irc.Conn, channel string) {
+	if key := joiner.conf.channelKey(channel); key != "" {
+		conn.Join(channel, key)
+	} else {
+		conn.Join(channel)
+	}
+}
+
+func (joiner *channelJoiner) stopTimers() {
+	for channel, timer := range joiner.invites {
+		timer.Stop()
+		delete(joiner.invites, channel)
+	}
+
+	if joiner.timer != nil {
+		joiner.timer.Stop()
+		joiner.timer = nil
+	}
+}
+
+// missingChannels returns the channels not joined yet.
+func (joiner *channelJoiner) missingChannels() []string {
+	joiner.mtx.Lock()
+	defer joiner.mtx.Unlock()
+
+	missing := make([]string, 0)
+	for _, channel := range joiner.channels {
+		if !joiner.joined[strings.ToLower(channel)] {
+			missing = append(missing, channel)
+		}
+	}
+	return missing
+}
+
+func (joiner *channelJoiner) handleJoin(conn *irc.Conn, line *irc.Line) {
+	if line.Nick != conn.Me().Nick || !util.ContainsFold(joiner.channels, line.Args[0]) {
+		return
+	}
+
+	joiner.mtx.Lock()
+	channel := strings.ToLower(line.Args[0])
+	joiner.joined[channel] = true
+	if timer, ok := joiner.invites[channel]; ok {
+		timer.Stop()
+		delete(joiner.invites, channel)
+	}
+
+	complete := !joiner.done && len(joiner.joined) == len(joiner.channels)
+	if complete {
+		joiner.done = true
+		joiner.stopTimers()
+	}
+	joiner.mtx.Unlock()
+
+	if complete {
+		joiner.onJoined(conn)
+	}
+}
+
+func (joiner *channelJoiner) handleInvite(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) < 2 {
+		return
+	}
+
+	channel := line.Args[1]
+	if util.ContainsFold(joiner.channels, channel) || util.ContainsFold(joiner.inviters, line.Nick) {
+		joiner.joinChannel(conn, channel)
+	}
+}
+
+// handleJoinError fails when a required channel cannot be joined.
+func (joiner *channelJoiner) handleJoinError(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) < 2 || !util.ContainsFold(joiner.channels, line.Args[1]) {
+		return
+	}
+
+	channel := line.Args[1]
+	joiner.mtx.Lock()
+	defer joiner.mtx.Unlock()
+	if joiner.done {
+		return
+	}
+
+	// wait for someone, e.g. the bot, to invite us
+	if line.Cmd == errInviteOnlyChan {
+		key := strings.ToLower(channel)
+		if _, waiting := joiner.invites[key]; !waiting {
+			joiner.invites[key] = time.AfterFunc(inviteTimeout, func() {
+				joiner.fail(fmt.Errorf("cannot join %s: %s", channel, ErrInviteTimeout))
+			})
+		}
+		return
+	}
+
+	go joiner.fail(fmt.Errorf("cannot join %s: %s", channel, line.Text()))
+}
+
+func (joiner *channelJoiner) fail(err error) {
+	joiner.mtx.Lock()
+	if joiner.done {
+		joiner.mtx.Unlock()
+		return
+	}
+	joiner.done = true
+	joiner.stopTimers()
+	joiner.mtx.Unlock()
+
+	joiner.onFailed(err)
+}
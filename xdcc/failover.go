@@ -0,0 +1,110 @@
+package xdcc
+
+import "sync"
+
+// failoverTransfer downloads a file from an ordered list of sources, switching to the next one
+// whenever the current source fails and resuming the download from where it was interrupted.
+type failoverTransfer struct {
+	conf    Config
+	sources []IRCFile
+	current Transfer
+	events  chan TransferEvent
+
+	mtx     sync.Mutex
+	stopped bool
+}
+
+func newFailoverTransfer(c Config) *failoverTransfer {
+	return &failoverTransfer{
+		conf:    c,
+		sources: append([]IRCFile{c.File}, c.Mirrors...),
+		events:  make(chan TransferEvent, defaultEventChanSize),
+	}
+}
+
+func (t *failoverTransfer) startSource(index int) error {
+	conf := t.conf
+	conf.File = t.sources[index]
+	conf.Mirrors = nil
+	conf.Resume = t.conf.Resume || index > 0
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.stopped {
+		return ErrTransferStopped
+	}
+
+	t.current = NewTransfer(conf)
+	return t.current.Start()
+}
+
+func (t *failoverTransfer) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.stopped = true
+	if t.current != nil {
+		t.current.Stop()
+	}
+}
+
+func (t *failoverTransfer) currentEvents() chan TransferEvent {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.current.PollEvents()
+}
+
+// Start connects to the first reachable source and begins forwarding its events.
+func (t *failoverTransfer) Start() error {
+	var err error
+	for i := range t.sources {
+		if err = t.startSource(i); err == nil {
+			go t.forwardEvents(i)
+			return nil
+		}
+	}
+	return err
+}
+
+func (t *failoverTransfer) forwardEvents(index int) {
+	started := false
+	for {
+		e := <-t.currentEvents()
+		switch evt := e.(type) {
+		case *TransferStartedEvent:
+			// the file has already been announced by a previous source
+			if started {
+				continue
+			}
+			started = true
+		case *TransferRestartedEvent:
+			started = false
+		case *TransferAbortedEvent:
+			next := t.nextSource(index)
+			if next < 0 {
+				t.events <- evt
+				return
+			}
+			index = next
+			continue
+		case *TransferCompletedEvent, *TransferVerificationFailedEvent:
+			t.events <- evt
+			return
+		}
+		t.events <- e
+	}
+}
+
+// nextSource starts the first working source after index, returning its position or -1.
+func (t *failoverTransfer) nextSource(index int) int {
+	for i := index + 1; i < len(t.sources); i++ {
+		if err := t.startSource(i); err == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *failoverTransfer) PollEvents() chan TransferEvent {
+	return t.events
+}
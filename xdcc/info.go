@@ -0,0 +1,107 @@
+package xdcc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type XdccInfoReq struct {
+	Slot int
+}
+
+func (info *XdccInfoReq) String() string {
+	return fmt.Sprintf("xdcc info #%d", info.Slot)
+}
+
+// XdccInfo holds the details of a pack, as reported by the bot in reply to an XDCC INFO request.
+type XdccInfo struct {
+	FileName string
+	FileSize int64
+	Added    string
+	Gets     int
+	MD5      string
+	CRC32    string
+
+	// Fields contains all the key-value pairs of the reply, including unknown ones.
+	Fields map[string]string
+	Keys   []string
+}
+
+var ErrInvalidXdccInfo = errors.New("invalid xdcc info reply")
+
+var xdccInfoFieldRegex = regexp.MustCompile(`^\s*(\S+(?: \S+)*?)\s{2,}(.+)$`)
+
+// ParseXdccInfo parses the reply of an iroffer-like bot to an XDCC INFO request, e.g.:
+//
+//	Pack Info for Pack #1:
+//	 Filename       file.mkv
+//	 Filesize       367001600 [350MB]
+//	 Last Modified  Sun Jan 10 12:00:00 2021 UTC
+//	 Gets           12
+//	 md5sum         0123456789abcdef0123456789abcdef
+//	 crc32          ABCD1234
+func ParseXdccInfo(lines []string) (*XdccInfo, error) {
+	info := &XdccInfo{
+		FileSize: -1,
+		Fields:   make(map[string]string),
+		Keys:     make([]string, 0),
+	}
+
+	for _, line := range lines {
+		// field values such as file names may contain the words of a rejection
+		matches := xdccInfoFieldRegex.FindStringSubmatch(line)
+		if matches == nil {
+			if isRejectionNotice(line) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidXdccInfo, strings.TrimSpace(line))
+			}
+			continue
+		}
+
+		key, value := matches[1], strings.TrimSpace(matches[2])
+		info.Fields[key] = value
+		info.Keys = append(info.Keys, key)
+
+		switch strings.ToLower(key) {
+		case "filename":
+			info.FileName = value
+		case "filesize":
+			info.FileSize, _ = strconv.ParseInt(firstField(value), 10, 64)
+		case "pack added", "last modified":
+			if info.Added == "" || strings.EqualFold(key, "pack added") {
+				info.Added = value
+			}
+		case "gets":
+			info.Gets, _ = strconv.Atoi(firstField(value))
+		case "md5sum":
+			info.MD5 = value
+		case "crc32":
+			info.CRC32 = value
+		}
+	}
+
+	if info.FileName == "" {
+		return nil, ErrInvalidXdccInfo
+	}
+	return info, nil
+}
+
+// firstField returns the first word of value, or an empty string if there is none.
+func firstField(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// QueryInfo asks the bot offering file for the details of its pack.
+func QueryInfo(file IRCFile, sslOnly bool) (*XdccInfo, error) {
+	lines, err := QueryBot(file.GetBot(), sslOnly, &XdccInfoReq{Slot: file.Slot})
+	if err != nil {
+		return nil, err
+	}
+	return ParseXdccInfo(lines)
+}
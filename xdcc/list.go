@@ -0,0 +1,65 @@
+package xdcc
+
+import (
+	"strings"
+)
+
+type XdccListReq struct{}
+
+func (list *XdccListReq) String() string {
+	return "xdcc list"
+}
+
+// XdccPacklistReq asks the bot to send its packlist as a text file through DCC.
+type XdccPacklistReq struct{}
+
+func (list *XdccPacklistReq) String() string {
+	return "xdcc send -1"
+}
+
+func isPackLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+func hasPackLines(lines []string) bool {
+	for _, line := range lines {
+		if isPackLine(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPacks retrieves the packlist of a bot, first through XDCC LIST and, if the bot
+// does not list its packs that way, by asking for the packlist file.
+func ListPacks(bot IRCBot, sslOnly bool) ([]string, error) {
+	lines, err := QueryBot(bot, sslOnly, &XdccListReq{})
+	if err == nil && hasPackLines(lines) {
+		return lines, nil
+	}
+
+	lines, err = QueryBot(bot, sslOnly, &XdccPacklistReq{})
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+type XdccSearchReq struct {
+	Terms string
+}
+
+func (search *XdccSearchReq) String() string {
+	return "xdcc search " + search.Terms
+}
+
+// TriggerReq is a channel trigger, such as "!search" or "@find", which makes
+// all the bots of a channel reply with the packs matching terms.
+type TriggerReq struct {
+	Trigger string
+	Terms   string
+}
+
+func (trigger *TriggerReq) String() string {
+	return trigger.Trigger + " " + trigger.Terms
+}
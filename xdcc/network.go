@@ -0,0 +1,224 @@
+package xdcc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// NetworkConfig holds the connection settings of an IRC network.
+// Empty fields fall back to the defaults set through SetNetworkConfigs.
+type NetworkConfig struct {
+	// Address is the host of the server, when different from the network name.
+	Address string `toml:"address,omitempty"`
+	Port    int    `toml:"port,omitempty"`
+
+	// TLS forces (true) or disables (false) TLS connections. When unset, TLS is tried first.
+	TLS *bool `toml:"tls,omitempty"`
+
+	// ServerPassword is sent to the server on connection, e.g. to log into a bouncer.
+	ServerPassword string `toml:"server_password,omitempty"`
+
+	// CertFile and KeyFile are the PEM files of the client certificate presented on TLS
+	// connections (CertFP). KeyFile may be empty when CertFile holds the key as well.
+	CertFile string `toml:"cert_file,omitempty"`
+	KeyFile  string `toml:"key_file,omitempty"`
+
+	// CAFile is a PEM bundle of the certificate authorities trusted for the server,
+	// in place of the ones of the system.
+	CAFile string `toml:"ca_file,omitempty"`
+
+	Nick string `toml:"nick,omitempty"`
+
+	// AltNicks are tried in order when Nick is already in use.
+	AltNicks []string `toml:"alt_nicks,omitempty"`
+
+	Username string `toml:"username,omitempty"`
+	Realname string `toml:"realname,omitempty"`
+
+	// Auth is the authentication method: "sasl", "external", "nickserv" or "none".
+	// When empty, SASL PLAIN is tried if a password is set, falling back to NickServ.
+	Auth string `toml:"auth,omitempty"`
+
+	// Account is the name of the account to identify with, Nick when empty.
+	Account string `toml:"account,omitempty"`
+
+	// Password is the password of the account.
+	Password string `toml:"password,omitempty"`
+
+	// PasswordEnv is the environment variable holding the password, when Password is empty.
+	PasswordEnv string `toml:"password_env,omitempty"`
+
+	// Channels must be joined, together with the channel of the bot, before requesting packs.
+	Channels []string `toml:"channels,omitempty"`
+
+	// ChannelKeys holds the keys of the channels requiring one, keyed by channel name.
+	ChannelKeys map[string]string `toml:"channel_keys,omitempty"`
+
+	// Bots holds the settings of specific bots, keyed by bot name.
+	Bots map[string]BotConfig `toml:"bots,omitempty"`
+
+	// loaded from CertFile, KeyFile and CAFile
+	certificates []tls.Certificate
+	rootCAs      *x509.CertPool
+}
+
+var (
+	defaultNetworkConfig NetworkConfig
+	networkConfigs       = make(map[string]NetworkConfig)
+)
+
+// SetNetworkConfigs sets the settings used to connect to IRC networks: defaults applies to
+// all networks, while networks holds the settings of specific networks, keyed by network name.
+// Certificate files are loaded right away, failing if any of them cannot be read.
+func SetNetworkConfigs(defaults NetworkConfig, networks map[string]NetworkConfig) error {
+	if err := defaults.loadTLSFiles(); err != nil {
+		return err
+	}
+
+	confs := make(map[string]NetworkConfig, len(networks))
+	for name, conf := range networks {
+		if err := conf.loadTLSFiles(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		confs[strings.ToLower(name)] = conf
+	}
+
+	defaultNetworkConfig = defaults
+	networkConfigs = confs
+	return nil
+}
+
+// MergeNetworkConfig fills the settings left unset in conf with the ones of defaults.
+func MergeNetworkConfig(defaults NetworkConfig, conf NetworkConfig) NetworkConfig {
+	if conf.Address == "" {
+		conf.Address = defaults.Address
+	}
+	if conf.Port == 0 {
+		conf.Port = defaults.Port
+	}
+	if conf.TLS == nil {
+		conf.TLS = defaults.TLS
+	}
+	if conf.ServerPassword == "" {
+		conf.ServerPassword = defaults.ServerPassword
+	}
+	if conf.CertFile == "" {
+		conf.CertFile = defaults.CertFile
+		conf.KeyFile = defaults.KeyFile
+		conf.certificates = defaults.certificates
+	}
+	if conf.CAFile == "" {
+		conf.CAFile = defaults.CAFile
+		conf.rootCAs = defaults.rootCAs
+	}
+	if conf.Nick == "" {
+		conf.Nick = defaults.Nick
+	}
+	if len(conf.AltNicks) == 0 {
+		conf.AltNicks = defaults.AltNicks
+	}
+	if conf.Username == "" {
+		conf.Username = defaults.Username
+	}
+	if conf.Realname == "" {
+		conf.Realname = defaults.Realname
+	}
+	if conf.Auth == "" {
+		conf.Auth = defaults.Auth
+	}
+	if conf.Account == "" {
+		conf.Account = defaults.Account
+	}
+	if conf.Password == "" {
+		conf.Password = defaults.Password
+	}
+	if conf.PasswordEnv == "" {
+		conf.PasswordEnv = defaults.PasswordEnv
+	}
+	if len(conf.Channels) == 0 {
+		conf.Channels = defaults.Channels
+	}
+	conf.ChannelKeys = mergeMaps(defaults.ChannelKeys, conf.ChannelKeys)
+	if len(conf.Bots) == 0 {
+		conf.Bots = defaults.Bots
+	}
+	return conf
+}
+
+func mergeMaps(defaults map[string]string, m map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return m
+	}
+
+	merged := make(map[string]string, len(defaults)+len(m))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range m {
+		merged[k] = v
+	}
+	return merged
+}
+
+// GetNetworkConfig returns the effective settings of a network.
+func GetNetworkConfig(network string) NetworkConfig {
+	return MergeNetworkConfig(defaultNetworkConfig, networkConfigs[strings.ToLower(network)])
+}
+
+// nickGenerator returns the function choosing a new nick when the current one is in use:
+// the alternates are tried in order, then random digits are appended to the nick.
+func (conf *NetworkConfig) nickGenerator(nick string) func(string) string {
+	next := 0
+	return func(string) string {
+		if next < len(conf.AltNicks) {
+			next++
+			return conf.AltNicks[next-1]
+		}
+		return nick + strconv.Itoa(int(rand.Uint32()%10000))
+	}
+}
+
+// server returns the address of the server of the network, with the port if configured.
+func (conf *NetworkConfig) server(network string) string {
+	host := network
+	if conf.Address != "" {
+		host = conf.Address
+	}
+
+	if conf.Port > 0 {
+		return net.JoinHostPort(host, strconv.Itoa(conf.Port))
+	}
+	return host
+}
+
+// host returns the host name of the server of the network.
+func (conf *NetworkConfig) host(network string) string {
+	if conf.Address != "" {
+		return conf.Address
+	}
+	return network
+}
+
+// sslOnly tells whether connections to the network must use TLS. Credentials are
+// never sent in plain text or to unverified servers, unless TLS is disabled explicitly.
+func (conf *NetworkConfig) sslOnly(sslOnly bool) bool {
+	if conf.TLS != nil {
+		return sslOnly || *conf.TLS
+	}
+	return sslOnly || conf.hasCredentials()
+}
+
+// hasCredentials tells whether a password, account or server password is configured.
+func (conf *NetworkConfig) hasCredentials() bool {
+	return conf.password() != "" || conf.Account != "" || conf.ServerPassword != ""
+}
+
+// plainOnly tells whether TLS is disabled for the network.
+func (conf *NetworkConfig) plainOnly() bool {
+	return conf.TLS != nil && !*conf.TLS
+}
@@ -0,0 +1,233 @@
+package xdcc
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"xdcc-cli/util"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	defaultQueryTimeout     = time.Minute
+	defaultQueryIdleTimeout = 5 * time.Second
+)
+
+// QueryConfig describes a request sent to one or more bots on the same network,
+// whose replies are collected as text lines.
+type QueryConfig struct {
+	Network string
+	SSLOnly bool
+
+	// Channels are joined before sending the request.
+	Channels []string
+
+	// Targets are the nicks or channels the request is sent to.
+	Targets []string
+
+	// AnySender enables collecting replies from any nick, including messages sent to
+	// the joined channels, rather than only private replies from the targets.
+	AnySender bool
+
+	// Timeout is the maximum amount of time to wait for the replies.
+	Timeout time.Duration
+
+	// IdleTimeout is the amount of time without any new reply after which the bots
+	// are considered done answering.
+	IdleTimeout time.Duration
+}
+
+// Reply is a line of text received from a bot.
+type Reply struct {
+	Nick string
+	Text string
+}
+
+var ErrNoReply = errors.New("no reply from bot")
+
+// Query joins the configured channels, sends req to the targets and returns the lines
+// of their replies, received either as notices, private messages or DCC sent text files.
+func Query(c QueryConfig, req CTCPRequest) ([]Reply, error) {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultQueryTimeout
+	}
+
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultQueryIdleTimeout
+	}
+
+	session := newQuerySession()
+	defer close(session.done)
+
+	conn, err := connectQuery(c, req, session)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Quit()
+
+	lines := make([]Reply, 0)
+
+	timeout := time.NewTimer(c.Timeout)
+	defer timeout.Stop()
+
+	idle := time.NewTimer(c.Timeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case line := <-session.replies:
+			lines = append(lines, line)
+			idle.Reset(c.IdleTimeout)
+		case <-idle.C:
+			return lines, nil
+		case err := <-session.errs:
+			return nil, err
+		case <-timeout.C:
+			if len(lines) == 0 {
+				return nil, ErrNoReply
+			}
+			return lines, nil
+		}
+	}
+}
+
+// QueryBot sends req to a single bot and returns the text of its replies.
+func QueryBot(bot IRCBot, sslOnly bool, req CTCPRequest) ([]string, error) {
+	replies, err := Query(QueryConfig{
+		Network:  bot.Network,
+		SSLOnly:  sslOnly,
+		Channels: []string{bot.Channel},
+		Targets:  []string{bot.Name},
+	}, req)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(replies))
+	for _, r := range replies {
+		lines = append(lines, r.Text)
+	}
+	return lines, nil
+}
+
+// querySession collects the replies of a query until it is done.
+type querySession struct {
+	replies chan Reply
+	errs    chan error
+	done    chan struct{}
+}
+
+func newQuerySession() *querySession {
+	return &querySession{
+		replies: make(chan Reply, defaultEventChanSize),
+		errs:    make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// push sends a reply, waiting for it to be collected unless the query is over.
+// It returns false once the query is over.
+func (s *querySession) push(nick string, text string) bool {
+	select {
+	case s.replies <- Reply{Nick: nick, Text: util.StripIRCFormatting(text)}:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// fail ends the query with err, unless it already failed.
+func (s *querySession) fail(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+func connectQuery(c QueryConfig, req CTCPRequest, session *querySession) (*irc.Conn, error) {
+	netConf := GetNetworkConfig(c.Network)
+	if !netConf.plainOnly() {
+		conn := irc.Client(newIRCConfig(c.Network, true, false))
+		setupQueryHandlers(conn, c, req, session)
+
+		err := conn.Connect()
+		if err == nil || netConf.sslOnly(c.SSLOnly) {
+			return conn, err
+		}
+	}
+
+	conn := irc.Client(newIRCConfig(c.Network, false, false))
+	setupQueryHandlers(conn, c, req, session)
+	return conn, conn.Connect()
+}
+
+func setupQueryHandlers(conn *irc.Conn, c QueryConfig, req CTCPRequest, session *querySession) {
+	// send the request once all the channels have been joined
+	netConf := GetNetworkConfig(c.Network)
+	channels := make([]string, 0, len(c.Channels))
+	for _, target := range c.Targets {
+		for _, channel := range netConf.channelsFor(target, "") {
+			if !util.ContainsFold(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	for _, channel := range c.Channels {
+		if !util.ContainsFold(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	joiner := setupChannelJoins(conn, c.Network, channels, c.Targets,
+		func(conn *irc.Conn) {
+			for _, target := range c.Targets {
+				conn.Privmsg(target, req.String())
+			}
+		}, session.fail)
+	setupAuthentication(conn, c.Network, joiner.join, session.fail)
+
+	isReply := func(line *irc.Line) bool {
+		return c.AnySender || (util.ContainsFold(c.Targets, line.Nick) && !line.Public())
+	}
+
+	onReply := func(conn *irc.Conn, line *irc.Line) {
+		if isReply(line) {
+			session.push(line.Nick, line.Text())
+		}
+	}
+	conn.HandleFunc(irc.NOTICE, onReply)
+	conn.HandleFunc(irc.PRIVMSG, onReply)
+
+	// some bots reply by sending a text file (e.g. a packlist) through DCC
+	conn.HandleFunc(irc.CTCP,
+		func(conn *irc.Conn, line *irc.Line) {
+			if line.Args[0] != DCC || !isReply(line) {
+				return
+			}
+
+			res, err := parseCTCPRes(line.Text())
+			if send, ok := res.(*XdccSendRes); err == nil && ok {
+				go receiveReplyFile(line.Nick, send, session)
+			}
+		})
+}
+
+// receiveReplyFile downloads a text file sent by a bot, pushing each of its lines as a reply.
+func receiveReplyFile(nick string, send *XdccSendRes, session *querySession) {
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: send.IP, Port: send.Port})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(conn, int64(send.FileSize)))
+	for scanner.Scan() {
+		if !session.push(nick, strings.TrimRight(scanner.Text(), "\r")) {
+			return
+		}
+	}
+}
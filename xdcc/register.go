@@ -0,0 +1,133 @@
+package xdcc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+
+	irc "github.com/fluffle/goirc/client"
+	"golang.org/x/net/proxy"
+)
+
+// registrationScheme is the proxy scheme of the connections sending lines before registering:
+// the client library sends NICK and USER as soon as it is connected, with no way to send
+// anything before them, so those lines are written by the dialer of the connection.
+const registrationScheme = "xdcc-cli-register"
+
+const (
+	defaultPort    = 6667
+	defaultSSLPort = 6697
+)
+
+// query parameters of the registration proxy URL
+const (
+	registrationNetworkParam  = "network"
+	registrationTLSParam      = "tls"
+	registrationInsecureParam = "insecure"
+)
+
+var ErrUnknownRegistration = errors.New("unknown registration")
+
+type registrationDialer struct {
+	forward   proxy.ContextDialer
+	tlsConfig *tls.Config // nil for plain text connections
+	lines     []string
+}
+
+func init() {
+	proxy.RegisterDialerType(registrationScheme, newRegistrationDialer)
+}
+
+// newRegistrationDialer builds the dialer of a connection from its proxy URL. The lines
+// and TLS settings are read from the configuration of the network on each connection,
+// so nothing is kept once the connection is done with.
+func newRegistrationDialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	query := u.Query()
+	network := query.Get(registrationNetworkParam)
+	if network == "" {
+		return nil, ErrUnknownRegistration
+	}
+
+	contextForward, ok := forward.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("dialer does not support context")
+	}
+
+	netConf := GetNetworkConfig(network)
+	d := &registrationDialer{forward: contextForward, lines: registrationLines(&netConf)}
+	if query.Get(registrationTLSParam) != "" {
+		d.tlsConfig = netConf.tlsConfig(network, query.Get(registrationInsecureParam) != "")
+	}
+	return d, nil
+}
+
+// registrationLines returns the lines sent before NICK and USER: the server password
+// and the request of the SASL capability.
+func registrationLines(netConf *NetworkConfig) []string {
+	var lines []string
+	if netConf.ServerPassword != "" {
+		lines = append(lines, "PASS "+netConf.ServerPassword)
+	}
+	if netConf.useSASL() {
+		lines = append(lines, capCommand+" "+capSubcmdReq+" :"+capSasl)
+	}
+	return lines
+}
+
+func (d *registrationDialer) Dial(network string, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *registrationDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.tlsConfig != nil {
+		tlsConn := tls.Client(conn, d.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	for _, line := range d.lines {
+		if _, err := conn.Write([]byte(line + "\r\n")); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// sendBeforeRegistration makes connections with config send the registration lines of
+// the network before NICK and USER. TLS is then performed by the dialer as well, so
+// config.Server must be set first for the default port to match the TLS setting.
+func sendBeforeRegistration(config *irc.Config, network string, skipCertificateCheck bool) {
+	config.Pass = ""
+
+	if _, _, err := net.SplitHostPort(config.Server); err != nil {
+		port := defaultPort
+		if config.SSL {
+			port = defaultSSLPort
+		}
+		config.Server = net.JoinHostPort(config.Server, strconv.Itoa(port))
+	}
+
+	query := url.Values{}
+	query.Set(registrationNetworkParam, network)
+	if config.SSL {
+		query.Set(registrationTLSParam, "1")
+		if skipCertificateCheck {
+			query.Set(registrationInsecureParam, "1")
+		}
+		config.SSL = false
+	}
+
+	config.Proxy = (&url.URL{Scheme: registrationScheme, RawQuery: query.Encode()}).String()
+}
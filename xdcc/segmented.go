@@ -0,0 +1,193 @@
+package xdcc
+
+import (
+	"fmt"
+	"sync"
+)
+
+type TransferSegmentStartedEvent struct {
+	Segment  int
+	Segments int
+	FileName string
+	Size     uint64
+}
+
+type TransferSegmentProgressEvent struct {
+	Segment       int
+	TransferBytes uint64
+	TransferRate  float32
+}
+
+type TransferSegmentCompletedEvent struct {
+	Segment int
+}
+
+// segmentedTransfer downloads a file from several sources at once,
+// each source sending a different byte range of the same file.
+type segmentedTransfer struct {
+	conf      Config
+	sources   []IRCFile
+	transfers []Transfer
+	events    chan TransferEvent
+
+	mtx       sync.Mutex
+	fileName  string
+	fileSize  uint64
+	started   bool
+	completed int
+	finished  bool
+}
+
+func newSegmentedTransfer(c Config) *segmentedTransfer {
+	sources := append([]IRCFile{c.File}, c.Mirrors...)
+	if len(sources) > c.Segments {
+		sources = sources[:c.Segments]
+	}
+
+	return &segmentedTransfer{
+		conf:    c,
+		sources: sources,
+		events:  make(chan TransferEvent, defaultEventChanSize),
+	}
+}
+
+func (t *segmentedTransfer) Start() error {
+	for i, source := range t.sources {
+		conf := t.conf
+		conf.File = source
+		conf.Mirrors = nil
+		conf.Segments = len(t.sources)
+		conf.segment = i
+
+		transfer := NewTransfer(conf)
+		if err := transfer.Start(); err != nil {
+			t.stopTransfers()
+			return err
+		}
+		t.transfers = append(t.transfers, transfer)
+	}
+
+	for i, transfer := range t.transfers {
+		go t.forwardEvents(i, transfer)
+	}
+	return nil
+}
+
+func (t *segmentedTransfer) forwardEvents(segment int, transfer Transfer) {
+	for {
+		e := <-transfer.PollEvents()
+
+		t.mtx.Lock()
+		if t.finished {
+			t.mtx.Unlock()
+			return
+		}
+
+		switch evt := e.(type) {
+		case *TransferStartedEvent:
+			if !t.started {
+				t.started = true
+				t.fileName = evt.FileName
+				t.fileSize = evt.FileSize
+				t.events <- evt
+			}
+
+			// each segment writes to the file named by its bot, which must be the same
+			mismatch := ""
+			if evt.FileName != t.fileName {
+				mismatch = "file name mismatch"
+			} else if evt.FileSize != t.fileSize {
+				mismatch = "file size mismatch"
+			}
+
+			if mismatch != "" {
+				t.finished = true
+				t.stopTransfers()
+				t.events <- &TransferAbortedEvent{
+					Error: fmt.Sprintf("%s: %s", t.sources[segment].String(), mismatch),
+				}
+				t.mtx.Unlock()
+				return
+			}
+
+			start, end := segmentRange(int(evt.FileSize), segment, len(t.sources))
+			if segment == len(t.sources)-1 {
+				end = int(evt.FileSize)
+			}
+
+			t.events <- &TransferSegmentStartedEvent{
+				Segment:  segment,
+				Segments: len(t.sources),
+				FileName: evt.FileName,
+				Size:     uint64(end - start),
+			}
+		case *TransferProgessEvent:
+			t.events <- evt
+			t.events <- &TransferSegmentProgressEvent{
+				Segment:       segment,
+				TransferBytes: evt.TransferBytes,
+				TransferRate:  evt.TransferRate,
+			}
+		case *TransferCompletedEvent:
+			t.events <- &TransferSegmentCompletedEvent{Segment: segment}
+
+			t.completed++
+			if t.completed == len(t.sources) {
+				t.finished = true
+				t.verify()
+			}
+			t.mtx.Unlock()
+			return
+		case *TransferAbortedEvent:
+			t.finished = true
+			t.stopTransfers()
+			t.events <- &TransferAbortedEvent{
+				Error: fmt.Sprintf("%s: %s", t.sources[segment].String(), evt.Error),
+			}
+			t.mtx.Unlock()
+			return
+		}
+		t.mtx.Unlock()
+	}
+}
+
+// verify checks the assembled file against its expected checksums.
+func (t *segmentedTransfer) verify() {
+	path := t.conf.OutPath + "/" + t.fileName
+
+	hasher := newFileHasher()
+	if err := hasher.hashFile(path, -1); err != nil {
+		t.events <- &TransferAbortedEvent{Error: err.Error()}
+		return
+	}
+
+	verified, failure := hasher.verify(expectedChecksums(t.conf.CRC32, t.conf.MD5, t.conf.OutPath, t.fileName))
+	if failure != nil {
+		t.events <- failure
+		return
+	}
+
+	for _, c := range verified {
+		t.events <- &TransferVerifiedEvent{Checksum: c}
+	}
+	t.events <- &TransferCompletedEvent{}
+}
+
+// stopTransfers stops the transfers of all the segments, ending their event forwarding.
+func (t *segmentedTransfer) stopTransfers() {
+	for _, transfer := range t.transfers {
+		transfer.Stop()
+	}
+}
+
+func (t *segmentedTransfer) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.finished = true
+	t.stopTransfers()
+}
+
+func (t *segmentedTransfer) PollEvents() chan TransferEvent {
+	return t.events
+}
@@ -0,0 +1,171 @@
+package xdcc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BotStats is the transfer history of a bot.
+type BotStats struct {
+	Network string
+	Channel string
+	Name    string
+
+	Successes int
+	Failures  int
+
+	// AverageSpeed is the mean of the average transfer rates, in bytes per second,
+	// of the transfers which received any data.
+	AverageSpeed float64
+	SpeedSamples int
+
+	// AverageQueueWait is the mean time spent in the queue of the bot, among the transfers queued.
+	AverageQueueWait time.Duration
+	QueueSamples     int
+
+	LastUsed time.Time
+}
+
+// Bot returns the irc://network/channel/bot url of the bot.
+func (s *BotStats) Bot() IRCBot {
+	return IRCBot{Network: s.Network, Channel: s.Channel, Name: s.Name}
+}
+
+// Reliability estimates the probability of a transfer from the bot succeeding, starting
+// from even odds for bots without history.
+func (s *BotStats) Reliability() float64 {
+	return float64(s.Successes+1) / float64(s.Successes+s.Failures+2)
+}
+
+// TransferRecord is the outcome of a transfer from a bot.
+type TransferRecord struct {
+	Success   bool
+	Speed     float64
+	Queued    bool
+	QueueWait time.Duration
+}
+
+// BotStatsStore keeps the transfer history of bots on disk.
+type BotStatsStore struct {
+	Path string
+	mtx  sync.Mutex
+}
+
+// DefaultBotStatsPath returns the location of the bot stats, under $XDG_DATA_HOME.
+func DefaultBotStatsPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "xdcc-cli", "bots.json")
+}
+
+func NewBotStatsStore(path string) *BotStatsStore {
+	return &BotStatsStore{Path: path}
+}
+
+// BotKey identifies a bot regardless of the channel it has been found in.
+func BotKey(network string, name string) string {
+	return strings.ToLower(network) + "/" + strings.ToLower(name)
+}
+
+func (store *BotStatsStore) load() (map[string]*BotStats, error) {
+	stats := make(map[string]*BotStats)
+
+	data, err := ioutil.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &stats)
+	return stats, err
+}
+
+// Load returns the stats of all the known bots, keyed by BotKey.
+func (store *BotStatsStore) Load() (map[string]*BotStats, error) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	return store.load()
+}
+
+// Record adds the outcome of a transfer to the history of bot.
+func (store *BotStatsStore) Record(bot IRCBot, r TransferRecord) error {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	all, err := store.load()
+	if err != nil {
+		return err
+	}
+
+	key := BotKey(bot.Network, bot.Name)
+	stats, ok := all[key]
+	if !ok {
+		stats = &BotStats{Network: bot.Network, Name: bot.Name}
+		all[key] = stats
+	}
+	stats.Channel = bot.Channel
+	stats.LastUsed = time.Now()
+
+	if r.Success {
+		stats.Successes++
+	} else {
+		stats.Failures++
+	}
+
+	if r.Speed > 0 {
+		stats.AverageSpeed = (stats.AverageSpeed*float64(stats.SpeedSamples) + r.Speed) / float64(stats.SpeedSamples+1)
+		stats.SpeedSamples++
+	}
+
+	if r.Queued {
+		total := stats.AverageQueueWait*time.Duration(stats.QueueSamples) + r.QueueWait
+		stats.QueueSamples++
+		stats.AverageQueueWait = total / time.Duration(stats.QueueSamples)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(store.Path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(store.Path, data, 0644)
+}
+
+const (
+	reliabilityWeight = 0.75
+	speedWeight       = 0.25
+)
+
+// Reputations scores each known bot in the range [0, 1], mostly by its reliability
+// and partly by its speed, relative to the fastest known bot.
+func Reputations(all map[string]*BotStats) map[string]float64 {
+	maxSpeed := 0.0
+	for _, s := range all {
+		maxSpeed = math.Max(maxSpeed, s.AverageSpeed)
+	}
+
+	reputations := make(map[string]float64, len(all))
+	for key, s := range all {
+		speedScore := 0.0
+		if maxSpeed > 0 {
+			speedScore = math.Log1p(s.AverageSpeed) / math.Log1p(maxSpeed)
+		}
+		reputations[key] = reliabilityWeight*s.Reliability() + speedWeight*speedScore
+	}
+	return reputations
+}
@@ -0,0 +1,55 @@
+package xdcc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// loadTLSFiles loads the client certificate and the certificate authorities of the network.
+func (conf *NetworkConfig) loadTLSFiles() error {
+	conf.certificates = nil
+	if conf.CertFile != "" {
+		keyFile := conf.KeyFile
+		if keyFile == "" {
+			keyFile = conf.CertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, keyFile)
+		if err != nil {
+			return err
+		}
+		conf.certificates = []tls.Certificate{cert}
+	}
+
+	conf.rootCAs = nil
+	if conf.CAFile != "" {
+		data, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return err
+		}
+
+		conf.rootCAs = x509.NewCertPool()
+		if !conf.rootCAs.AppendCertsFromPEM(data) {
+			return errors.New(conf.CAFile + ": no certificate found")
+		}
+	}
+	return nil
+}
+
+// allowInsecure tells whether connections to the network may skip the verification
+// of the certificate of the server.
+func (conf *NetworkConfig) allowInsecure() bool {
+	return conf.CAFile == "" && conf.CertFile == ""
+}
+
+// tlsConfig returns the TLS settings of connections to the network.
+func (conf *NetworkConfig) tlsConfig(network string, skipCertificateCheck bool) *tls.Config {
+	return &tls.Config{
+		ServerName:         conf.host(network),
+		InsecureSkipVerify: skipCertificateCheck,
+		Certificates:       conf.certificates,
+		RootCAs:            conf.rootCAs,
+	}
+}
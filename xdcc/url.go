@@ -20,7 +20,11 @@ type IRCBot struct {
 	Name    string
 }
 
-const ircFileURLFields = 4
+const (
+	ircFileURLFields    = 4
+	ircBotURLFields     = 3
+	ircChannelURLFields = 2
+)
 
 func parseSlot(slotStr string) (int, error) {
 	if strings.HasPrefix(slotStr, "#") {
@@ -60,6 +64,56 @@ func ParseURL(url string) (*IRCFile, error) {
 	return fileUrl, nil
 }
 
+// bot url has the following format: irc://network/channel/bot
+func ParseBotURL(url string) (*IRCBot, error) {
+	if !strings.HasPrefix(url, "irc://") {
+		return nil, ErrInvalidURL
+	}
+
+	fields := strings.Split(strings.TrimSuffix(strings.TrimPrefix(url, "irc://"), "/"), "/")
+	if len(fields) != ircBotURLFields {
+		return nil, ErrInvalidURL
+	}
+
+	bot := &IRCBot{
+		Network: fields[0],
+		Channel: fields[1],
+		Name:    fields[2],
+	}
+
+	if !strings.HasPrefix(bot.Channel, "#") {
+		bot.Channel = "#" + bot.Channel
+	}
+	return bot, nil
+}
+
+// channel url has the following format: irc://network/channel
+func ParseChannelURL(url string) (network string, channel string, err error) {
+	if !strings.HasPrefix(url, "irc://") {
+		return "", "", ErrInvalidURL
+	}
+
+	fields := strings.Split(strings.TrimSuffix(strings.TrimPrefix(url, "irc://"), "/"), "/")
+	if len(fields) != ircChannelURLFields {
+		return "", "", ErrInvalidURL
+	}
+
+	channel = fields[1]
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	return fields[0], channel, nil
+}
+
+func (bot *IRCBot) String() string {
+	return fmt.Sprintf("irc://%s/%s/%s", bot.Network, bot.Channel, bot.Name)
+}
+
+// GetFile returns the url of the file offered by the bot at the given slot.
+func (bot *IRCBot) GetFile(slot int) IRCFile {
+	return IRCFile{Network: bot.Network, Channel: bot.Channel, UserName: bot.Name, Slot: slot}
+}
+
 func (url *IRCFile) GetBot() IRCBot {
 	return IRCBot{Network: url.Network, Channel: url.Channel, Name: url.UserName}
 }
@@ -0,0 +1,207 @@
+package xdcc
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	CRC32 = "crc32"
+	MD5   = "md5"
+)
+
+type Checksum struct {
+	Algorithm string
+	Value     string
+}
+
+type TransferVerifiedEvent struct {
+	Checksum
+}
+
+// TransferRestartedEvent is notified when a download starts over after a failed verification.
+type TransferRestartedEvent struct {
+	Reason string
+}
+
+type TransferVerificationFailedEvent struct {
+	Checksum
+	Actual string
+}
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+func (e *TransferVerificationFailedEvent) Error() string {
+	return fmt.Sprintf("%s: expected %s %s, got %s", ErrChecksumMismatch, e.Algorithm, e.Value, e.Actual)
+}
+
+// fileHasher computes the checksums of a file while it is being written.
+type fileHasher struct {
+	crc32 hash.Hash32
+	md5   hash.Hash
+	io.Writer
+}
+
+func newFileHasher() *fileHasher {
+	h := &fileHasher{
+		crc32: crc32.NewIEEE(),
+		md5:   md5.New(),
+	}
+	h.Writer = io.MultiWriter(h.crc32, h.md5)
+	return h
+}
+
+// hashFile feeds the first n bytes of the file at path to the hasher,
+// or the whole file if n is negative.
+func (h *fileHasher) hashFile(path string, n int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if n >= 0 {
+		reader = io.LimitReader(file, n)
+	}
+
+	_, err = io.Copy(h, bufio.NewReader(reader))
+	return err
+}
+
+func (h *fileHasher) sum(algorithm string) string {
+	switch algorithm {
+	case CRC32:
+		return fmt.Sprintf("%08X", h.crc32.Sum32())
+	case MD5:
+		return hex.EncodeToString(h.md5.Sum(nil))
+	}
+	return ""
+}
+
+// verify compares the computed checksums against the expected ones, returning
+// the first mismatch, if any, and the list of successfully verified checksums.
+func (h *fileHasher) verify(expected []Checksum) ([]Checksum, *TransferVerificationFailedEvent) {
+	verified := make([]Checksum, 0)
+	for _, c := range expected {
+		actual := h.sum(c.Algorithm)
+		if !strings.EqualFold(actual, c.Value) {
+			return nil, &TransferVerificationFailedEvent{Checksum: c, Actual: actual}
+		}
+		verified = append(verified, c)
+	}
+	return verified, nil
+}
+
+// only square brackets are matched, parentheses usually hold dates such as "(20210315)"
+var fileNameCRCRegex = regexp.MustCompile(`\[([0-9A-Fa-f]{8})\]`)
+
+// CRC32FromFileName extracts a CRC32 checksum embedded in a file name, as in "[ABCD1234]".
+func CRC32FromFileName(fileName string) (string, bool) {
+	matches := fileNameCRCRegex.FindAllStringSubmatch(fileName, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return strings.ToUpper(matches[len(matches)-1][1]), true
+}
+
+// lookupChecksumFiles searches the SFV and MD5 files in dir for a checksum of fileName.
+func lookupChecksumFiles(dir string, fileName string) []Checksum {
+	checksums := make([]Checksum, 0)
+
+	sfvFiles, _ := filepath.Glob(filepath.Join(dir, "*.sfv"))
+	for _, path := range sfvFiles {
+		if value, ok := lookupChecksumFile(path, fileName, false); ok {
+			checksums = append(checksums, Checksum{Algorithm: CRC32, Value: value})
+			break
+		}
+	}
+
+	md5Files, _ := filepath.Glob(filepath.Join(dir, "*.md5"))
+	for _, path := range md5Files {
+		if value, ok := lookupChecksumFile(path, fileName, true); ok {
+			checksums = append(checksums, Checksum{Algorithm: MD5, Value: value})
+			break
+		}
+	}
+	return checksums
+}
+
+// lookupChecksumFile searches a checksum file for fileName. SFV files have lines of the form
+// "filename checksum", while md5sum files have lines of the form "checksum [*]filename".
+func lookupChecksumFile(path string, fileName string, checksumFirst bool) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		var name, value string
+		if checksumFirst {
+			idx := strings.IndexAny(line, " \t")
+			if idx < 0 {
+				continue
+			}
+			value, name = line[:idx], strings.TrimPrefix(strings.TrimSpace(line[idx:]), "*")
+		} else {
+			idx := strings.LastIndexAny(line, " \t")
+			if idx < 0 {
+				continue
+			}
+			name, value = strings.TrimSpace(line[:idx]), line[idx+1:]
+		}
+
+		if strings.EqualFold(name, fileName) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// expectedChecksums collects the known checksums of fileName: the ones reported by the bot,
+// the one embedded in the file name and the ones listed by any checksum file found in dir.
+func expectedChecksums(botCRC32 string, botMD5 string, dir string, fileName string) []Checksum {
+	checksums := make([]Checksum, 0)
+	if botCRC32 != "" {
+		checksums = append(checksums, Checksum{Algorithm: CRC32, Value: botCRC32})
+	} else if crc, ok := CRC32FromFileName(fileName); ok {
+		checksums = append(checksums, Checksum{Algorithm: CRC32, Value: crc})
+	}
+
+	if botMD5 != "" {
+		checksums = append(checksums, Checksum{Algorithm: MD5, Value: botMD5})
+	}
+
+	for _, checksum := range lookupChecksumFiles(dir, fileName) {
+		if !hasAlgorithm(checksums, checksum.Algorithm) {
+			checksums = append(checksums, checksum)
+		}
+	}
+	return checksums
+}
+
+func hasAlgorithm(checksums []Checksum, algorithm string) bool {
+	for _, c := range checksums {
+		if c.Algorithm == algorithm {
+			return true
+		}
+	}
+	return false
+}
@@ -2,16 +2,15 @@ package xdcc
 
 import (
 	"bufio"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	irc "github.com/fluffle/goirc/client"
@@ -36,6 +35,29 @@ func (send *XdccSendReq) String() string {
 	return fmt.Sprintf("xdcc send #%d", send.Slot)
 }
 
+type XdccRemoveReq struct{}
+
+func (remove *XdccRemoveReq) String() string {
+	return "xdcc remove"
+}
+
+type DccResumeReq struct {
+	FileName string
+	Port     int
+	Position int
+}
+
+func (resume *DccResumeReq) String() string {
+	return fmt.Sprintf("RESUME %s %d %d", quoteFileName(resume.FileName), resume.Port, resume.Position)
+}
+
+func quoteFileName(fileName string) string {
+	if strings.Contains(fileName, " ") {
+		return "\"" + fileName + "\""
+	}
+	return fileName
+}
+
 type XdccSendRes struct {
 	FileName string
 	IP       net.IP
@@ -85,19 +107,78 @@ func (send *XdccSendRes) Parse(args []string) error {
 	return nil
 }
 
+type DccAcceptRes struct {
+	FileName string
+	Port     int
+	Position int
+}
+
+const DccAcceptResArgs = 3
+
+func (accept *DccAcceptRes) Name() string {
+	return ACCEPT
+}
+
+func (accept *DccAcceptRes) Parse(args []string) error {
+	if len(args) != DccAcceptResArgs {
+		return errors.New("invalid number of arguments")
+	}
+
+	accept.FileName = args[0]
+
+	var err error
+	accept.Port, err = strconv.Atoi(args[1])
+	if err != nil {
+		return err
+	}
+
+	accept.Position, err = strconv.Atoi(args[2])
+	return err
+}
+
 const (
+	DCC     = "DCC"
 	SEND    = "SEND"
+	RESUME  = "RESUME"
+	ACCEPT  = "ACCEPT"
 	VERSION = "\x01VERSION\x01"
 )
 
+// splitCTCPArgs splits text on white spaces, keeping double quoted file names together.
+func splitCTCPArgs(text string) []string {
+	fields := make([]string, 0)
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		if text[0] == '"' {
+			if end := strings.Index(text[1:], "\""); end >= 0 {
+				fields = append(fields, text[1:end+1])
+				text = text[end+2:]
+				continue
+			}
+		}
+
+		end := strings.IndexAny(text, " \t")
+		if end < 0 {
+			end = len(text)
+		}
+		fields = append(fields, text[:end])
+		text = text[end:]
+	}
+	return fields
+}
+
 func parseCTCPRes(text string) (CTCPResponse, error) {
-	fields := strings.Fields(text)
+	fields := splitCTCPArgs(text)
+	if len(fields) == 0 {
+		return nil, errors.New("empty CTCP message")
+	}
 
 	var resp CTCPResponse = nil
 
 	switch strings.TrimSpace(fields[0]) {
 	case SEND:
 		resp = &XdccSendRes{}
+	case ACCEPT:
+		resp = &DccAcceptRes{}
 	case VERSION:
 		return nil, nil
 	}
@@ -130,8 +211,14 @@ const maxConnAttempts = 5
 type Transfer interface {
 	Start() error
 	PollEvents() chan TransferEvent
+
+	// Stop ends the transfer, closing its connections. A TransferAbortedEvent is
+	// sent if the transfer was still running.
+	Stop()
 }
 
+var ErrTransferStopped = errors.New("transfer stopped")
+
 type retryTransfer struct {
 	*XdccTransfer
 	conf Config
@@ -144,10 +231,13 @@ func (t *retryTransfer) Start() error {
 		return nil
 	}
 
-	t2 := newXdccTransfer(t.conf, true, true)
-	if err := t1.conn.Connect(); err == nil {
-		t.XdccTransfer = t2
-		return nil
+	// a configured certificate authority or client certificate must not be bypassed
+	if netConf := GetNetworkConfig(t.conf.File.Network); netConf.allowInsecure() {
+		t2 := newXdccTransfer(t.conf, true, true)
+		if err := t2.conn.Connect(); err == nil {
+			t.XdccTransfer = t2
+			return nil
+		}
 	}
 
 	t.XdccTransfer = newXdccTransfer(t.conf, false, false)
@@ -158,54 +248,154 @@ func (t *retryTransfer) PollEvents() chan TransferEvent {
 	return t.XdccTransfer.PollEvents()
 }
 
+func (t *retryTransfer) Stop() {
+	if t.XdccTransfer != nil {
+		t.XdccTransfer.Stop()
+	}
+}
+
 type XdccTransfer struct {
-	filePath     string
-	url          IRCFile
-	conn         *irc.Conn
-	connAttempts int
-	started      bool
-	events       chan TransferEvent
+	filePath       string
+	url            IRCFile
+	conn           *irc.Conn
+	connAttempts   int
+	requested      bool
+	queued         bool
+	started        bool
+	finished       bool
+	resume         bool
+	segment        int
+	segments       int
+	rangeEnd       int
+	expectedCRC32  string
+	expectedMD5    string
+	redownloads    int
+	pendingSend    *XdccSendRes
+	requestTimeout time.Duration
+	queueTimeout   time.Duration
+	finishOnce     sync.Once
+	events         chan TransferEvent
+
+	// mtx guards the state shared by IRC handlers, timers and the download
+	mtx     sync.Mutex
+	dccConn net.Conn
+
+	stats        *BotStatsStore
+	queuedAt     time.Time
+	queueWait    time.Duration
+	speedSum     float64
+	speedSamples int
 }
 
 type Config struct {
 	File    IRCFile
 	OutPath string
 	SSLOnly bool
+
+	// Mirrors is an ordered list of alternative sources of the same file.
+	// When a source fails, the download resumes from the next one.
+	Mirrors []IRCFile
+
+	// Resume enables resuming the download of a partially downloaded file
+	// through DCC RESUME instead of starting over.
+	Resume bool
+
+	// RequestTimeout is the maximum amount of time to wait for the bot to start
+	// sending the file once the request has been sent. Zero means no timeout.
+	RequestTimeout time.Duration
+
+	// QueueTimeout is the maximum amount of time to wait in the queue of a bot
+	// before giving up. Zero means no timeout.
+	QueueTimeout time.Duration
+
+	// Segments is the maximum number of sources (among File and Mirrors) the file
+	// is downloaded from in parallel, each one sending a different byte range.
+	// Values lower than two disable segmented downloads.
+	Segments int
+
+	// CRC32 and MD5 are the checksums reported by the bot, if known. Downloaded files are
+	// verified against them, against a CRC32 embedded in the file name and against
+	// SFV and md5sum files found in the output folder.
+	CRC32 string
+	MD5   string
+
+	// Redownloads is the number of times a file failing verification is downloaded again.
+	Redownloads int
+
+	// Stats, if set, records the outcome of the transfer in the history of the bot.
+	Stats *BotStatsStore
+
+	segment int
 }
 
 func NewTransfer(c Config) Transfer {
-	if c.SSLOnly {
+	if c.Segments > 1 && len(c.Mirrors) > 0 {
+		return newSegmentedTransfer(c)
+	}
+
+	if len(c.Mirrors) > 0 {
+		return newFailoverTransfer(c)
+	}
+
+	netConf := GetNetworkConfig(c.File.Network)
+	if netConf.sslOnly(c.SSLOnly) {
 		return newXdccTransfer(c, true, false)
 	}
 
+	if netConf.plainOnly() {
+		return newXdccTransfer(c, false, false)
+	}
+
 	return &retryTransfer{
 		conf: c,
 	}
 }
 
-func newXdccTransfer(c Config, enableSSL bool, skipCertificateCheck bool) *XdccTransfer {
+func newIRCConfig(network string, enableSSL bool, skipCertificateCheck bool) *irc.Config {
+	netConf := GetNetworkConfig(network)
+
 	rand.Seed(time.Now().UTC().UnixNano())
 	nick := IRCClientUserName + strconv.Itoa(int(rand.Uint32()))
+	if netConf.Nick != "" {
+		nick = netConf.Nick
+	}
 
-	file := c.File
-
-	config := irc.NewConfig(nick)
+	// empty username and realname keep the defaults of the client library
+	config := irc.NewConfig(nick, netConf.Username, netConf.Realname)
 	config.SSL = enableSSL
-	config.SSLConfig = &tls.Config{ServerName: file.Network, InsecureSkipVerify: skipCertificateCheck}
-	config.Server = file.Network
-	config.NewNick = func(nick string) string {
-		return nick + "" + strconv.Itoa(int(rand.Uint32()))
+	config.SSLConfig = netConf.tlsConfig(network, skipCertificateCheck)
+	config.Pass = netConf.ServerPassword
+	config.Server = netConf.server(network)
+
+	// SASL is negotiated before registering, the server then waits for it to end
+	if netConf.useSASL() {
+		sendBeforeRegistration(config, network, skipCertificateCheck)
 	}
+	config.NewNick = netConf.nickGenerator(nick)
+	return config
+}
 
-	conn := irc.Client(config)
+func newXdccTransfer(c Config, enableSSL bool, skipCertificateCheck bool) *XdccTransfer {
+	file := c.File
+
+	conn := irc.Client(newIRCConfig(file.Network, enableSSL, skipCertificateCheck))
 
 	t := &XdccTransfer{
-		conn:         conn,
-		url:          file,
-		filePath:     c.OutPath,
-		started:      false,
-		connAttempts: 0,
-		events:       make(chan TransferEvent, defaultEventChanSize),
+		conn:           conn,
+		url:            file,
+		filePath:       c.OutPath,
+		started:        false,
+		connAttempts:   0,
+		resume:         c.Resume,
+		segment:        c.segment,
+		segments:       c.Segments,
+		expectedCRC32:  c.CRC32,
+		expectedMD5:    c.MD5,
+		redownloads:    c.Redownloads,
+		requestTimeout: c.RequestTimeout,
+		queueTimeout:   c.QueueTimeout,
+		events:         make(chan TransferEvent, defaultEventChanSize),
+		stats:          c.Stats,
 	}
 	t.setupHandlers(file.Channel, file.UserName, file.Slot)
 	return t
@@ -215,55 +405,267 @@ func (transfer *XdccTransfer) send(req CTCPRequest) {
 	transfer.conn.Privmsg(transfer.url.UserName, req.String())
 }
 
+func (transfer *XdccTransfer) sendCTCP(req CTCPRequest) {
+	transfer.conn.Ctcp(transfer.url.UserName, DCC, req.String())
+}
+
+func (transfer *XdccTransfer) sendRequest() {
+	transfer.mtx.Lock()
+	transfer.requested = true
+	transfer.mtx.Unlock()
+
+	transfer.send(&XdccSendReq{Slot: transfer.url.Slot})
+
+	if transfer.requestTimeout > 0 {
+		time.AfterFunc(transfer.requestTimeout, func() {
+			transfer.mtx.Lock()
+			waiting := !transfer.started && !transfer.queued
+			transfer.mtx.Unlock()
+
+			if waiting {
+				transfer.abort(ErrRequestTimeout.Error())
+			}
+		})
+	}
+}
+
+func (transfer *XdccTransfer) isRequested() bool {
+	transfer.mtx.Lock()
+	defer transfer.mtx.Unlock()
+	return transfer.requested
+}
+
+func (transfer *XdccTransfer) isStarted() bool {
+	transfer.mtx.Lock()
+	defer transfer.mtx.Unlock()
+	return transfer.started
+}
+
+func (transfer *XdccTransfer) isFinished() bool {
+	transfer.mtx.Lock()
+	defer transfer.mtx.Unlock()
+	return transfer.finished
+}
+
+func (transfer *XdccTransfer) handleQueued() {
+	transfer.mtx.Lock()
+	if transfer.queued {
+		transfer.mtx.Unlock()
+		return
+	}
+	transfer.queued = true
+	transfer.queuedAt = time.Now()
+	transfer.mtx.Unlock()
+
+	if transfer.queueTimeout > 0 {
+		time.AfterFunc(transfer.queueTimeout, func() {
+			if !transfer.isStarted() {
+				transfer.send(&XdccRemoveReq{})
+				transfer.abort(ErrQueueTimeout.Error())
+			}
+		})
+	}
+}
+
+var (
+	ErrRequestTimeout = errors.New("timed out waiting for the bot to send the file")
+	ErrQueueTimeout   = errors.New("queued for too long")
+	ErrResumeTimeout  = errors.New("timed out waiting for the bot to accept the resume request")
+)
+
+// resumeAcceptTimeout is the time to wait for the bot to accept a DCC RESUME.
+const resumeAcceptTimeout = 30 * time.Second
+
+// finish marks the transfer as terminated, notifies e and leaves the network.
+func (transfer *XdccTransfer) finish(e TransferEvent) {
+	transfer.finishOnce.Do(func() {
+		transfer.mtx.Lock()
+		transfer.finished = true
+		transfer.mtx.Unlock()
+
+		transfer.recordStats(e)
+		transfer.notifyEvent(e)
+		if transfer.conn.Connected() {
+			transfer.conn.Quit()
+		}
+	})
+}
+
+// recordStats adds the outcome of the transfer, notified by e, to the history of the bot.
+func (transfer *XdccTransfer) recordStats(e TransferEvent) {
+	if transfer.stats == nil {
+		return
+	}
+
+	transfer.mtx.Lock()
+	r := TransferRecord{Queued: !transfer.queuedAt.IsZero(), QueueWait: transfer.queueWait}
+	if transfer.speedSamples > 0 {
+		r.Speed = transfer.speedSum / float64(transfer.speedSamples)
+	}
+	transfer.mtx.Unlock()
+
+	if _, ok := e.(*TransferCompletedEvent); ok {
+		r.Success = true
+	}
+
+	bot := IRCBot{Network: transfer.url.Network, Channel: transfer.url.Channel, Name: transfer.url.UserName}
+	transfer.stats.Record(bot, r) // failing to record stats does not affect the transfer
+}
+
+func (transfer *XdccTransfer) Stop() {
+	transfer.finishOnce.Do(func() {
+		transfer.mtx.Lock()
+		transfer.finished = true
+		if transfer.dccConn != nil {
+			transfer.dccConn.Close()
+		}
+		transfer.mtx.Unlock()
+
+		transfer.notifyEvent(&TransferAbortedEvent{Error: ErrTransferStopped.Error()})
+		if transfer.conn.Connected() {
+			transfer.conn.Quit()
+		}
+	})
+}
+
+func (transfer *XdccTransfer) abort(reason string) {
+	transfer.finish(&TransferAbortedEvent{Error: reason})
+}
+
+// notices sent by bots when a request is refused
+var rejectionNotices = []string{
+	"invalid pack number",
+	"you already requested",
+	"you can only have",
+	"denied",
+	"not allowed",
+	"you must be on a known channel",
+	"closing connection",
+	"queue is full",
+	"is full, try again later",
+	"already have a pack queued",
+	"you already have",
+}
+
+func isRejectionNotice(text string) bool {
+	text = strings.ToLower(text)
+	for _, notice := range rejectionNotices {
+		if strings.Contains(text, notice) {
+			return true
+		}
+	}
+	return false
+}
+
+// notices sent by bots when a request is queued, rejections being checked first
+var queueNotices = []string{
+	"added you to the main queue",
+	"added you to the idle queue",
+	"queueing you for pack",
+	"queue for pack",
+	"queue position",
+	"you have been queued",
+}
+
+func isQueueNotice(text string) bool {
+	text = strings.ToLower(text)
+	for _, notice := range queueNotices {
+		if strings.Contains(text, notice) {
+			return true
+		}
+	}
+	return false
+}
+
 func (transfer *XdccTransfer) setupHandlers(channel string, userName string, slot int) {
 	conn := transfer.conn
 
-	// e.g. join channel on connect.
 	conn.HandleFunc(irc.CONNECTED,
 		func(conn *irc.Conn, line *irc.Line) {
+			transfer.mtx.Lock()
 			transfer.connAttempts = 0
-			conn.Join(channel)
+			transfer.requested = false
+			transfer.mtx.Unlock()
 		})
 
 	conn.HandleFunc(irc.ERROR, func(conn *irc.Conn, line *irc.Line) {
 
 	})
 
-	// send xdcc send on successfull join
-	conn.HandleFunc(irc.JOIN,
-		func(conn *irc.Conn, line *irc.Line) {
-			if strings.EqualFold(line.Args[0], channel) && !transfer.started {
-				transfer.send(&XdccSendReq{Slot: slot})
+	// send xdcc send once all the channels required by the bot are joined
+	netConf := GetNetworkConfig(transfer.url.Network)
+	joiner := setupChannelJoins(conn, transfer.url.Network, netConf.channelsFor(userName, channel), []string{userName},
+		func(conn *irc.Conn) {
+			if !transfer.isRequested() {
+				transfer.sendRequest()
 			}
+		},
+		func(err error) {
+			transfer.abort(err.Error())
+		})
+
+	// join channels once identified, channels may be restricted to registered users
+	setupAuthentication(conn, transfer.url.Network, joiner.join,
+		func(err error) {
+			transfer.abort(err.Error())
 		})
 
 	conn.HandleFunc(irc.PRIVMSG, func(conn *irc.Conn, line *irc.Line) {})
 
+	// abort the transfer if the bot refuses the request
+	conn.HandleFunc(irc.NOTICE,
+		func(conn *irc.Conn, line *irc.Line) {
+			if !strings.EqualFold(line.Nick, userName) || transfer.isStarted() {
+				return
+			}
+
+			if isRejectionNotice(line.Text()) {
+				transfer.abort(line.Text())
+			} else if isQueueNotice(line.Text()) {
+				transfer.handleQueued()
+			}
+		})
+
 	conn.HandleFunc(irc.CTCP,
 		func(conn *irc.Conn, line *irc.Line) {
+			if line.Args[0] != DCC {
+				return
+			}
+
 			res, err := parseCTCPRes(line.Text())
 			if err != nil {
-				fmt.Println(err.Error())
-				os.Exit(1) // TODO: correct clean up
+				transfer.abort(err.Error())
+				return
 			}
 			transfer.handleCTCPRes(res)
 		})
 
 	conn.HandleFunc(irc.DISCONNECTED,
 		func(conn *irc.Conn, line *irc.Line) {
+			if transfer.isFinished() {
+				return
+			}
+
+			transfer.mtx.Lock()
+			attempts := transfer.connAttempts
+			transfer.connAttempts++
+			transfer.mtx.Unlock()
+
 			var err error = nil
 
-			if transfer.connAttempts < maxConnAttempts {
+			if attempts < maxConnAttempts {
 				time.Sleep(time.Second)
 
 				err = conn.Connect()
 			}
 
-			if (err != nil || transfer.connAttempts >= maxConnAttempts) && !transfer.started {
-				transfer.notifyEvent(&TransferAbortedEvent{Error: err.Error()})
+			if (err != nil || attempts >= maxConnAttempts) && !transfer.isStarted() {
+				reason := "too many connection attempts"
+				if err != nil {
+					reason = err.Error()
+				}
+				transfer.abort(reason)
 			}
-
-			transfer.connAttempts++
 		})
 }
 
@@ -327,62 +729,256 @@ func (monitor *SpeedMonitorReader) Read(buf []byte) (int, error) {
 	return n, err
 }
 
+func (transfer *XdccTransfer) outFilePath(fileName string) string {
+	return transfer.filePath + "/" + fileName
+}
+
+// segmentRange returns the byte range [start, end) of a segment of a file.
+func segmentRange(fileSize int, segment int, segments int) (int, int) {
+	return fileSize / segments * segment, fileSize / segments * (segment + 1)
+}
+
 func (transfer *XdccTransfer) handleXdccSendRes(send *XdccSendRes) {
-	go func() {
-		conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: send.IP, Port: send.Port})
-		if err != nil {
-			log.Fatalf("unable to reach host %s:%d", send.IP.String(), send.Port)
+	if transfer.segments > 1 {
+		start, end := segmentRange(send.FileSize, transfer.segment, transfer.segments)
+		if transfer.segment == transfer.segments-1 {
+			end = send.FileSize
+		}
+		transfer.rangeEnd = end
+
+		if start > 0 {
+			transfer.requestResume(send, start)
 			return
 		}
+		go transfer.download(send, 0)
+		return
+	}
 
-		file, err := os.OpenFile(transfer.filePath+"/"+send.FileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		fileWriter := bufio.NewWriter(file)
+	if transfer.resume {
+		info, err := os.Stat(transfer.outFilePath(send.FileName))
+		if err == nil && info.Size() > 0 && info.Size() < int64(send.FileSize) {
+			transfer.requestResume(send, int(info.Size()))
+			return
+		}
+	}
+	go transfer.download(send, 0)
+}
 
-		if err != nil {
-			log.Fatal(err.Error())
+// requestResume asks the bot to send the file starting from position. Bots ignoring the
+// request get the whole file downloaded, unless only a segment of it is wanted.
+func (transfer *XdccTransfer) requestResume(send *XdccSendRes, position int) {
+	transfer.mtx.Lock()
+	transfer.pendingSend = send
+	transfer.mtx.Unlock()
+
+	transfer.sendCTCP(&DccResumeReq{FileName: send.FileName, Port: send.Port, Position: position})
+
+	time.AfterFunc(resumeAcceptTimeout, func() {
+		transfer.mtx.Lock()
+		pending := transfer.pendingSend == send
+		if pending {
+			transfer.pendingSend = nil
+		}
+		transfer.mtx.Unlock()
+
+		if !pending {
 			return
 		}
 
-		transfer.notifyEvent(&TransferStartedEvent{
-			FileName: send.FileName,
-			FileSize: uint64(send.FileSize),
-		})
-		transfer.started = true
+		if transfer.segments > 1 {
+			transfer.abort(ErrResumeTimeout.Error())
+			return
+		}
+		transfer.download(send, 0)
+	})
+}
 
-		reader := NewSpeedMonitorReader(conn, func(dowloadedAmount int, speed float64) {
-			transfer.notifyEvent(&TransferProgessEvent{
-				TransferRate:  float32(speed),
-				TransferBytes: uint64(dowloadedAmount),
-			})
-		})
+func (transfer *XdccTransfer) handleDccAcceptRes(accept *DccAcceptRes) {
+	transfer.mtx.Lock()
+	send := transfer.pendingSend
+	if send == nil || send.Port != accept.Port {
+		transfer.mtx.Unlock()
+		return
+	}
+	transfer.pendingSend = nil
+	transfer.mtx.Unlock()
 
-		// download loop
-		downloadedBytesTotal := 0
-		buf := make([]byte, downloadBufSize)
-		for downloadedBytesTotal < send.FileSize {
-			n, err := reader.Read(buf)
+	go transfer.download(send, accept.Position)
+}
 
-			if err != nil {
-				log.Fatal(err.Error())
-				return
-			}
+// download receives the file from the bot, writing it starting from the given offset.
+func (transfer *XdccTransfer) download(send *XdccSendRes, offset int) {
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: send.IP, Port: send.Port})
+	if err != nil {
+		transfer.abort(fmt.Sprintf("unable to reach host %s:%d", send.IP.String(), send.Port))
+		return
+	}
+	defer conn.Close()
+
+	// the connection is closed to interrupt the download when the transfer is stopped
+	transfer.mtx.Lock()
+	if transfer.finished {
+		transfer.mtx.Unlock()
+		return
+	}
+	transfer.dccConn = conn
+	transfer.mtx.Unlock()
+
+	end := send.FileSize
+	if transfer.rangeEnd > 0 {
+		end = transfer.rangeEnd
+	}
 
-			if _, err := fileWriter.Write(buf[:n]); err != nil {
-				log.Fatal(err.Error())
+	// segments share the same file, which must not be truncated
+	segmented := transfer.segments > 1
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset == 0 && !segmented {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(transfer.outFilePath(send.FileName), flags, 0644)
+	if err != nil {
+		transfer.abort(err.Error())
+		return
+	}
+	defer file.Close()
+
+	if offset > 0 && !segmented {
+		if err := file.Truncate(int64(offset)); err != nil {
+			transfer.abort(err.Error())
+			return
+		}
+	}
+
+	// the ranges of the other segments are kept, the bytes of an older file past the end are not
+	if segmented {
+		if err := file.Truncate(int64(send.FileSize)); err != nil {
+			transfer.abort(err.Error())
+			return
+		}
+	}
+
+	if offset > 0 {
+		if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+			transfer.abort(err.Error())
+			return
+		}
+	}
+
+	fileWriter := bufio.NewWriter(file)
+
+	// segments are verified once the whole file has been assembled
+	var writer io.Writer = fileWriter
+	hasher := newFileHasher()
+	if !segmented {
+		if offset > 0 {
+			if err := hasher.hashFile(transfer.outFilePath(send.FileName), int64(offset)); err != nil {
+				transfer.abort(err.Error())
 				return
 			}
+		}
+		writer = io.MultiWriter(fileWriter, hasher)
+	}
 
-			downloadedBytesTotal += n
+	transfer.notifyEvent(&TransferStartedEvent{
+		FileName: send.FileName,
+		FileSize: uint64(send.FileSize),
+	})
+	transfer.mtx.Lock()
+	transfer.started = true
+	if !transfer.queuedAt.IsZero() && transfer.queueWait == 0 {
+		transfer.queueWait = time.Since(transfer.queuedAt)
+	}
+	transfer.mtx.Unlock()
+
+	reader := NewSpeedMonitorReader(conn, func(dowloadedAmount int, speed float64) {
+		transfer.mtx.Lock()
+		transfer.speedSum += speed
+		transfer.speedSamples++
+		transfer.mtx.Unlock()
+		transfer.notifyEvent(&TransferProgessEvent{
+			TransferRate:  float32(speed),
+			TransferBytes: uint64(dowloadedAmount),
+		})
+	})
+
+	// download loop
+	downloadedBytesTotal := offset
+	buf := make([]byte, downloadBufSize)
+	for downloadedBytesTotal < end {
+		chunk := buf
+		if end-downloadedBytesTotal < len(chunk) {
+			chunk = buf[:end-downloadedBytesTotal]
 		}
-		fileWriter.Flush()
 
-		transfer.notifyEvent(&TransferCompletedEvent{})
-	}()
+		n, err := reader.Read(chunk)
+
+		if err != nil {
+			fileWriter.Flush()
+			transfer.abort(err.Error())
+			return
+		}
+
+		if _, err := writer.Write(chunk[:n]); err != nil {
+			transfer.abort(err.Error())
+			return
+		}
+
+		downloadedBytesTotal += n
+	}
+
+	if err := fileWriter.Flush(); err != nil {
+		transfer.abort(err.Error())
+		return
+	}
+
+	if segmented {
+		transfer.finish(&TransferCompletedEvent{})
+		return
+	}
+	transfer.verify(send, hasher)
+}
+
+// verify checks the downloaded file against its expected checksums, downloading it again on failure
+// if allowed to.
+func (transfer *XdccTransfer) verify(send *XdccSendRes, hasher *fileHasher) {
+	expected := expectedChecksums(transfer.expectedCRC32, transfer.expectedMD5, transfer.filePath, send.FileName)
+
+	verified, failure := hasher.verify(expected)
+	if failure == nil {
+		for _, c := range verified {
+			transfer.notifyEvent(&TransferVerifiedEvent{Checksum: c})
+		}
+		transfer.finish(&TransferCompletedEvent{})
+		return
+	}
+
+	if transfer.redownloads <= 0 {
+		transfer.finish(failure)
+		return
+	}
+	transfer.redownloads--
+
+	if err := os.Remove(transfer.outFilePath(send.FileName)); err != nil {
+		transfer.abort(err.Error())
+		return
+	}
+
+	transfer.notifyEvent(&TransferRestartedEvent{Reason: failure.Error()})
+	transfer.mtx.Lock()
+	transfer.started = false
+	transfer.queued = false
+	transfer.mtx.Unlock()
+
+	transfer.sendRequest()
 }
 
 func (transfer *XdccTransfer) handleCTCPRes(resp CTCPResponse) {
 	switch r := resp.(type) {
 	case *XdccSendRes:
 		transfer.handleXdccSendRes(r)
+	case *DccAcceptRes:
+		transfer.handleDccAcceptRes(r)
 	}
 }